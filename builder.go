@@ -0,0 +1,250 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A FilterBuilder constructs a Filter programmatically. Conditions are only
+// validated when Build is called.
+type FilterBuilder struct {
+	p     *parser
+	steps []builderStep
+}
+
+type builderStep struct {
+	sep   string
+	key   string
+	op    string
+	value string
+}
+
+// NewFilterBuilder creates a new FilterBuilder. The options are interpreted
+// as they would be by NewParser.
+func NewFilterBuilder(options ...Option) *FilterBuilder {
+	return &FilterBuilder{p: NewParser(options...).(*parser)}
+}
+
+// Where adds the first condition. It is equivalent to And and is provided for
+// readability.
+func (b *FilterBuilder) Where(key, op, value string) *FilterBuilder {
+	return b.And(key, op, value)
+}
+
+// And adds a condition, linked to the previous one with AND.
+func (b *FilterBuilder) And(key, op, value string) *FilterBuilder {
+	b.steps = append(b.steps, builderStep{separatorAnd, key, op, value})
+	return b
+}
+
+// Or adds a condition, linked to the previous one with OR.
+func (b *FilterBuilder) Or(key, op, value string) *FilterBuilder {
+	b.steps = append(b.steps, builderStep{separatorOr, key, op, value})
+	return b
+}
+
+// Build validates the conditions and returns the resulting Filter, which is
+// equivalent to the one the Parser would produce from the corresponding filter
+// string. The separator of the first condition is ignored. The value is used
+// as-is, as Condition.StringValue would return it; it is quoted when needed. If
+// a key or operator is invalid, the error identifies the offending condition
+// by its (zero-based) position.
+func (b *FilterBuilder) Build() (Filter, error) {
+	cs := make([]condition, len(b.steps))
+	var seps []string
+	for i, st := range b.steps {
+		c, err := b.p.buildCondition(st.key, st.op, st.value)
+		if err != nil {
+			return nil, fmt.Errorf("condition %d (%s%s): %v", i, st.key, st.op, err)
+		}
+		cs[i] = c
+		if i > 0 {
+			seps = append(seps, st.sep)
+		}
+	}
+	f := newFilter(cs, seps)
+	f.ops = b.p.ops
+	return f, nil
+}
+
+// NewFilterFromConditions creates a Filter from the conditions, linking them
+// with the given separator ("AND" or "OR"). Any links the conditions already
+// have are ignored. An error is returned if the separator is invalid or if
+// there are no conditions.
+func NewFilterFromConditions(sep string, conditions ...Condition) (Filter, error) {
+	if sep != separatorAnd && sep != separatorOr {
+		return nil, fmt.Errorf("invalid separator %q, expected %s or %s", sep, separatorAnd, separatorOr)
+	}
+	if len(conditions) == 0 {
+		return nil, fmt.Errorf("no conditions")
+	}
+	cs := make([]condition, len(conditions))
+	seps := make([]string, len(conditions)-1)
+	for i, c := range conditions {
+		if c == nil {
+			return nil, fmt.Errorf("condition %d is nil", i)
+		}
+		cs[i] = toCondition(c)
+	}
+	for i := range seps {
+		seps[i] = sep
+	}
+	return newFilter(cs, seps), nil
+}
+
+// NewFilterFromMap creates a Filter with an equality condition for every map
+// entry, linked with AND. The conditions are ordered by key. An error is
+// returned if a key is not a valid (dotted) name.
+func NewFilterFromMap(m map[string]string) (Filter, error) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	cs := make([]condition, len(keys))
+	seps := make([]string, 0, len(keys))
+	p := &parser{}
+	for i, k := range keys {
+		key, parts, err := p.parseKey(k)
+		if err != nil {
+			return nil, err
+		}
+		cs[i] = condition{key, parts, "=", m[k], false, nil, nil, nil}
+		if i > 0 {
+			seps = append(seps, separatorAnd)
+		}
+	}
+	return newFilter(cs, seps), nil
+}
+
+// toCondition converts a Condition into a condition without links.
+func toCondition(c Condition) condition {
+	switch c := c.(type) {
+	case *condition:
+		return c.unlinked()
+	}
+	return condition{c.Key(), c.KeyParts(), c.Op(), c.StringValue(), c.IsQuoted(), nil, nil, nil}
+}
+
+// buildCondition creates a condition, validating the key and operator like the
+// parser would. Values that could only have been parsed from a quoted value
+// are marked as such.
+func (p *parser) buildCondition(key, op, value string) (condition, error) {
+	k, parts, err := p.parseKey(key)
+	if err != nil {
+		return condition{}, err
+	}
+	if !p.ops[op] {
+		return condition{}, fmt.Errorf("unknown operator %q", op)
+	}
+	quoted := needsQuotes(value) || filter{ops: p.ops}.extendsOperator(op, value)
+	return condition{k, parts, op, value, quoted, nil, nil, nil}, nil
+}
+
+// decodeCondition creates a condition from a decoded key, operator and
+// value, validating the key and operator like the parser would.
+func (p *parser) decodeCondition(key, op, value string, quoted bool) (condition, error) {
+	if key == "" {
+		return condition{}, fmt.Errorf("missing key")
+	}
+	k, parts, err := p.parseKey(key)
+	if err != nil {
+		return condition{}, err
+	}
+	if op == "" {
+		return condition{}, fmt.Errorf("missing operator")
+	}
+	if !p.ops[op] {
+		return condition{}, fmt.Errorf("unknown operator %q", op)
+	}
+	return condition{k, parts, op, value, quoted, nil, nil, nil}, nil
+}
+
+// parseKey parses a complete key.
+func (p *parser) parseKey(key string) (string, []string, error) {
+	k, parts, i, err := p.parseFullName(key, 0)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid key %q: %v", key, err)
+	}
+	if i != len(key) {
+		return "", nil, fmt.Errorf("invalid key %q: unexpected character at %d", key, i)
+	}
+	return k, parts, nil
+}
+
+// An OperatorSet is a set of operators.
+type OperatorSet map[string]bool
+
+// NewOperatorSet creates an OperatorSet containing the given operators.
+func NewOperatorSet(ops ...string) OperatorSet {
+	s := make(OperatorSet, len(ops))
+	for _, op := range ops {
+		s[op] = true
+	}
+	return s
+}
+
+// Contains reports whether the operator is in the set.
+func (s OperatorSet) Contains(op string) bool {
+	return s[op]
+}
+
+// A ConditionBuilder constructs a Condition. Unlike NewCondition, it validates
+// its input.
+type ConditionBuilder struct {
+	key   string
+	op    string
+	value string
+	ops   OperatorSet
+}
+
+// NewConditionBuilder creates a new ConditionBuilder.
+func NewConditionBuilder() *ConditionBuilder {
+	return &ConditionBuilder{}
+}
+
+// Key sets the condition's (dotted) key.
+func (b *ConditionBuilder) Key(k string) *ConditionBuilder {
+	b.key = k
+	return b
+}
+
+// Op sets the condition's operator.
+func (b *ConditionBuilder) Op(op string) *ConditionBuilder {
+	b.op = op
+	return b
+}
+
+// Value sets the condition's value. It is used as-is, as
+// Condition.StringValue would return it.
+func (b *ConditionBuilder) Value(v string) *ConditionBuilder {
+	b.value = v
+	return b
+}
+
+// WithOps restricts the operator to those in ops.
+func (b *ConditionBuilder) WithOps(ops OperatorSet) *ConditionBuilder {
+	b.ops = ops
+	return b
+}
+
+// Build validates the input and returns the resulting Condition. The key must
+// follow the naming rules of the filter grammar and the operator must not be
+// empty. If an OperatorSet has been specified, the operator must be in it.
+func (b *ConditionBuilder) Build() (Condition, error) {
+	k, parts, err := (&parser{}).parseKey(b.key)
+	if err != nil {
+		return nil, err
+	}
+	if b.op == "" {
+		return nil, fmt.Errorf("empty operator")
+	}
+	if b.ops != nil && !b.ops.Contains(b.op) {
+		return nil, fmt.Errorf("unknown operator %q", b.op)
+	}
+	return &condition{k, parts, b.op, b.value, false, nil, nil, nil}, nil
+}
@@ -0,0 +1,288 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFilterBuilder_Build(t *testing.T) {
+	ops := OptionOperators("<", ">=")
+	tests := []struct {
+		name    string
+		builder *FilterBuilder
+		equiv   string
+		opts    []Option
+		wantErr bool
+	}{
+		{"empty", NewFilterBuilder(), "", nil, false},
+		{"single", NewFilterBuilder().And("foo", "=", "bar"), "foo=bar", nil, false},
+		{"first separator ignored", NewFilterBuilder().Or("foo", "=", "bar"), "foo=bar", nil, false},
+		{
+			"and, or",
+			NewFilterBuilder().And("foo", "=", "bar").And("bla.vla", "!=", "moo").Or("foo", "=", "boo"),
+			"foo=bar AND bla.vla!=moo OR foo=boo",
+			nil,
+			false,
+		},
+		{
+			"registered operators",
+			NewFilterBuilder(ops).And("foo", ">=", "1").And("bar", "<", "2"),
+			"foo>=1 AND bar<2",
+			[]Option{ops},
+			false,
+		},
+		{
+			"casing",
+			NewFilterBuilder(OptionSnakeCase()).And("fooBar", "=", "1"),
+			"fooBar=1",
+			[]Option{OptionSnakeCase()},
+			false,
+		},
+		{
+			"where",
+			NewFilterBuilder().Where("status", "=", "open").And("age", "!=", "18").Or("vip", "=", "true"),
+			"status=open AND age!=18 OR vip=true",
+			nil,
+			false,
+		},
+		{
+			"quoted values",
+			NewFilterBuilder().Where("foo", "=", "bar moo").And("bla", "=", `"vla`).And("x", "=", `a\b`),
+			`foo="bar moo" AND bla="\"vla" AND x=a\b`,
+			nil,
+			false,
+		},
+		{
+			"value extending operator",
+			NewFilterBuilder(OptionOperators("<", "<=")).Where("foo", "<", "=1"),
+			`foo<"=1"`,
+			[]Option{OptionOperators("<", "<=")},
+			false,
+		},
+		{"empty value", NewFilterBuilder().Where("foo", "=", "").And("bar", "=", "1"), "foo= AND bar=1", nil, false},
+		{"! invalid key", NewFilterBuilder().And("1foo", "=", "bar"), "", nil, true},
+		{"! key with trailing characters", NewFilterBuilder().And("foo bar", "=", "bar"), "", nil, true},
+		{"! empty key", NewFilterBuilder().And("", "=", "bar"), "", nil, true},
+		{"! empty key part", NewFilterBuilder().And("foo..bar", "=", "bar"), "", nil, true},
+		{"! unregistered operator", NewFilterBuilder().And("foo", "<", "bar"), "", nil, true},
+		{"! empty operator", NewFilterBuilder().And("foo", "", "bar"), "", nil, true},
+		{"! invalid second", NewFilterBuilder().And("foo", "=", "bar").Or("_", "=", "bar"), "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.builder.Build()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Build() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			want, err := NewParser(tt.opts...).Parse(tt.equiv)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			if got.String() != want.String() {
+				t.Errorf("Build() got = %v, want %v", got, want)
+			}
+			gs, ws := got.Conditions(), want.Conditions()
+			if len(gs) != len(ws) {
+				t.Fatalf("Build() got = %v, want %v", gs, ws)
+			}
+			for i := range gs {
+				if !conditionsEqual(gs[i], ws[i]) || gs[i].IsQuoted() != ws[i].IsQuoted() {
+					t.Errorf("Build() got = %v, want %v", gs[i], ws[i])
+				}
+			}
+			for _, k := range want.Keys() {
+				g, _ := got.Get(k)
+				w, _ := want.Get(k)
+				if len(g) != len(w) {
+					t.Errorf("Get(%s) got = %v, want %v", k, g, w)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterBuilder_Build_error(t *testing.T) {
+	_, err := NewFilterBuilder().Where("foo", "=", "bar").Or("1bla", "=", "vla").Build()
+	if err == nil {
+		t.Fatal("Build() expected error")
+	}
+	if !strings.HasPrefix(err.Error(), "condition 1 (1bla=)") {
+		t.Errorf("Build() error = %v, want error for condition 1", err)
+	}
+}
+
+func TestConditionBuilder_Build(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *ConditionBuilder
+		want    Condition
+		wantErr bool
+	}{
+		{
+			"simple",
+			NewConditionBuilder().Key("foo").Op("=").Value("bar"),
+			NewCondition("foo", []string{"foo"}, "=", "bar"),
+			false,
+		},
+		{
+			"dotted key",
+			NewConditionBuilder().Key("foo.bar").Op("~").Value("bla"),
+			NewCondition("foo.bar", []string{"foo", "bar"}, "~", "bla"),
+			false,
+		},
+		{
+			"empty value",
+			NewConditionBuilder().Key("foo").Op("="),
+			NewCondition("foo", []string{"foo"}, "=", ""),
+			false,
+		},
+		{
+			"registered operator",
+			NewConditionBuilder().Key("foo").Op("<").Value("1").WithOps(NewOperatorSet("<", ">")),
+			NewCondition("foo", []string{"foo"}, "<", "1"),
+			false,
+		},
+		{"! invalid key", NewConditionBuilder().Key("1foo").Op("=").Value("bar"), nil, true},
+		{"! empty key", NewConditionBuilder().Op("=").Value("bar"), nil, true},
+		{"! key with trailing dot", NewConditionBuilder().Key("foo.").Op("=").Value("bar"), nil, true},
+		{"! empty operator", NewConditionBuilder().Key("foo").Value("bar"), nil, true},
+		{
+			"! unregistered operator",
+			NewConditionBuilder().Key("foo").Op("=").Value("1").WithOps(NewOperatorSet("<", ">")),
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.builder.Build()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Build() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && !conditionsEqual(got, tt.want) {
+				t.Errorf("Build() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFilterFromConditions(t *testing.T) {
+	parsed, _ := NewParser().Parse("foo=bar AND bla=vla OR moo=boo")
+	tests := []struct {
+		name       string
+		sep        string
+		conditions []Condition
+		want       string
+		wantErr    bool
+	}{
+		{
+			"single",
+			"AND",
+			[]Condition{NewCondition("foo", []string{"foo"}, "=", "bar")},
+			"foo=bar",
+			false,
+		},
+		{
+			"and",
+			"AND",
+			[]Condition{NewCondition("foo", []string{"foo"}, "=", "bar"), NewCondition("foo", []string{"foo"}, "!=", "bla")},
+			"foo=bar AND foo!=bla",
+			false,
+		},
+		{"or, relinked", "OR", parsed.Conditions(), "foo=bar OR bla=vla OR moo=boo", false},
+		{"and, relinked", "AND", parsed.Conditions(), "foo=bar AND bla=vla AND moo=boo", false},
+		{"! invalid separator", "and", parsed.Conditions(), "", true},
+		{"! no conditions", "AND", nil, "", true},
+		{"! nil condition", "AND", []Condition{nil}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewFilterFromConditions(tt.sep, tt.conditions...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewFilterFromConditions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if got.String() != tt.want {
+				t.Errorf("NewFilterFromConditions() got = %v, want %v", got, tt.want)
+			}
+			want, _ := NewParser().Parse(tt.want)
+			if !reflect.DeepEqual(got.Keys(), want.Keys()) {
+				t.Errorf("Keys() got = %v, want %v", got.Keys(), want.Keys())
+			}
+		})
+	}
+	if parsed.String() != "foo=bar AND bla=vla OR moo=boo" {
+		t.Errorf("source filter changed to %v", parsed)
+	}
+}
+
+func TestNewFilterFromConditions_roundTrip(t *testing.T) {
+	f, _ := NewParser().Parse("foo=bar AND bla=vla AND foo=boo")
+	got, err := NewFilterFromConditions("AND", f.Conditions()...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.String() != f.String() {
+		t.Errorf("got %v, want %v", got, f)
+	}
+	gs, ws := got.Conditions(), f.Conditions()
+	for i := range ws {
+		if !conditionsEqual(gs[i], ws[i]) {
+			t.Errorf("got %v, want %v", gs[i], ws[i])
+		}
+	}
+}
+
+func TestNewFilterFromMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       map[string]string
+		want    string
+		wantErr bool
+	}{
+		{"nil", nil, "", false},
+		{"single", map[string]string{"project": "myproject"}, "project=myproject", false},
+		{
+			"sorted",
+			map[string]string{"project": "myproject", "environment": "prod", "owner.id": "42"},
+			"environment=prod AND owner.id=42 AND project=myproject",
+			false,
+		},
+		{"empty value", map[string]string{"foo": ""}, "foo=", false},
+		{"! invalid key", map[string]string{"project": "myproject", "1foo": "bar"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewFilterFromMap(tt.m)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewFilterFromMap() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if got.String() != tt.want {
+				t.Errorf("NewFilterFromMap() got = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.m {
+				c, ok := got.GetFirst(k)
+				if !ok || c.Op() != "=" || c.StringValue() != v {
+					t.Errorf("GetFirst(%s) got = %v, want %s=%s", k, c, k, v)
+				}
+			}
+		})
+	}
+}
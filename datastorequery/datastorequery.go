@@ -0,0 +1,56 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+// Package datastorequery applies filters to Cloud Datastore queries. It is a
+// separate module, so that the listfilter package itself does not depend on
+// the Datastore client.
+package datastorequery
+
+import (
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/datastore"
+	"github.com/HayoVanLoon/go-listfilter"
+)
+
+// ErrUnsupportedFilter is returned (wrapped) for filters that cannot be
+// expressed as a Datastore query. Callers can fall back to evaluating such
+// filters in memory, for instance with listfilter.FilterSlice.
+var ErrUnsupportedFilter = errors.New("unsupported filter")
+
+// operators maps filter operators to Datastore operators. Equality on an
+// array property matches if any element is equal, which is what the has
+// operator ':' expresses.
+var operators = map[string]string{
+	"=":  "=",
+	"!=": "!=",
+	"<":  "<",
+	">":  ">",
+	"<=": "<=",
+	">=": ">=",
+	":":  "=",
+}
+
+// Apply returns a derivative of the query with a field filter for every
+// condition. Keys are used as property paths, with the dots separating the
+// properties of embedded entities, and values are converted with
+// listfilter.TypedValue.
+//
+// Datastore queries only combine filters with AND, so filters with OR
+// conditions result in an ErrUnsupportedFilter, as do conditions with other
+// operators than '=', '!=', '<', '>', '<=', '>=' and ':'.
+func Apply(q *datastore.Query, f listfilter.Filter) (*datastore.Query, error) {
+	for _, c := range f.Conditions() {
+		if _, or := c.AndOr(); or != nil {
+			return nil, fmt.Errorf("%w: datastore queries do not support OR (%s)", ErrUnsupportedFilter, c.Key())
+		}
+		op, ok := operators[c.Op()]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s: unsupported operator %s", ErrUnsupportedFilter, c.Key(), c.Op())
+		}
+		q = q.FilterField(c.Key(), op, listfilter.TypedValue(c))
+	}
+	return q, nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package datastorequery
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/HayoVanLoon/go-listfilter"
+)
+
+func TestApply(t *testing.T) {
+	q := datastore.NewQuery("Thing")
+	p := listfilter.NewParser(listfilter.OptionOperators("<", ">", "<=", ">=", ":", "~"))
+	tests := []struct {
+		name    string
+		query   string
+		want    *datastore.Query
+		wantErr bool
+	}{
+		{"empty", "", q, false},
+		{"single", "foo=bar", q.FilterField("foo", "=", "bar"), false},
+		{
+			"typed values",
+			`a=1 AND b=1.5 AND c=true AND d="1" AND e>=2024-03-01T12:00:00Z`,
+			q.FilterField("a", "=", int64(1)).
+				FilterField("b", "=", 1.5).
+				FilterField("c", "=", true).
+				FilterField("d", "=", "1").
+				FilterField("e", ">=", time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)),
+			false,
+		},
+		{"not equal", "foo!=bar", q.FilterField("foo", "!=", "bar"), false},
+		{"has", "tags:a", q.FilterField("tags", "=", "a"), false},
+		{"embedded", "owner.name<joe", q.FilterField("owner.name", "<", "joe"), false},
+		{"or", "a=1 OR a=2", nil, true},
+		{"or after and", "b=1 AND a=1 OR a=2", nil, true},
+		{"unsupported operator", "foo~bar", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error parsing %q: %v", tt.query, err)
+			}
+			actual, err := Apply(q, f)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnsupportedFilter) {
+					t.Errorf("expected ErrUnsupportedFilter, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(actual, tt.want) {
+				t.Errorf("expected %+v,\ngot %+v", tt.want, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Dedupe returns a new Filter without duplicate conditions. Conditions are
+// duplicates if their key, operator, value and quoting are the same. As OR binds
+// more tightly than AND, only two kinds of duplicates are removed: duplicates
+// within an OR group, and OR groups that consist of the same conditions as an
+// earlier OR group. Thus 'a=1 AND a=1' becomes 'a=1', but in 'a=1 OR b=2 AND
+// a=1' both occurrences of 'a=1' are kept. The first occurrence is always kept
+// and the remaining conditions are linked as described at Filter.Subfilter.
+func (f filter) Dedupe() Filter {
+	return f.dedupe()
+}
+
+func (f filter) dedupe() filter {
+	var drop []bool
+	seenGroups := make(map[string]bool)
+	var group []int
+	var groupKeys []string
+	seen := make(map[string]bool)
+	for c := f.first; c != nil; {
+		next, sep := c.next()
+		k := dedupeKey(c)
+		if seen[k] {
+			drop = append(drop, true)
+		} else {
+			seen[k] = true
+			drop = append(drop, false)
+			groupKeys = append(groupKeys, k)
+		}
+		group = append(group, len(drop)-1)
+		if sep != separatorOr {
+			sort.Strings(groupKeys)
+			gk := strings.Join(groupKeys, "\x01")
+			if seenGroups[gk] {
+				for _, i := range group {
+					drop[i] = true
+				}
+			}
+			seenGroups[gk] = true
+			group, groupKeys = nil, nil
+			seen = make(map[string]bool)
+		}
+		c = next
+	}
+	i := -1
+	return f.rewrite(func(c condition) (condition, bool) {
+		i += 1
+		return c, !drop[i]
+	})
+}
+
+// dedupeKey returns a string that uniquely identifies the condition's key,
+// operator, value and quoting.
+func dedupeKey(c *condition) string {
+	return conditionKey(c) + "\x00" + strconv.FormatBool(c.quoted)
+}
@@ -0,0 +1,67 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilter_Dedupe(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"empty", "", ""},
+		{"no duplicates", "foo=bar AND bla=vla", "foo=bar AND bla=vla"},
+		{"AND", "status=open AND status=open", "status=open"},
+		{"AND, not adjacent", "a=1 AND b=2 AND a=1 AND c=3", "a=1 AND b=2 AND c=3"},
+		{"within OR group", "a=1 OR b=2 OR a=1", "a=1 OR b=2"},
+		{"across OR group boundary", "a=1 OR b=2 AND a=1", "a=1 OR b=2 AND a=1"},
+		{"repeated OR group", "a=1 OR b=2 AND c=3 AND b=2 OR a=1", "a=1 OR b=2 AND c=3"},
+		{"repeated OR group, first", "a=1 OR b=2 AND a=1 OR b=2 AND c=3", "a=1 OR b=2 AND c=3"},
+		{"different operator", "a=1 AND a!=1", "a=1 AND a!=1"},
+		{"different value", "a=1 AND a=2", "a=1 AND a=2"},
+		{"different quoting", `a=1 AND a="1"`, `a=1 AND a="1"`},
+		{"same quoting", `a="1 2" AND a="1 2"`, `a="1 2"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewParser().Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got := f.Dedupe()
+			if got.String() != tt.want {
+				t.Errorf("Dedupe() = %v, want %v", got, tt.want)
+			}
+			if f.String() != tt.query {
+				t.Errorf("original changed to %v, want %v", f, tt.query)
+			}
+			want, err := NewParser().Parse(tt.want)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			if !reflect.DeepEqual(got.Keys(), want.Keys()) {
+				t.Errorf("Keys() = %v, want %v", got.Keys(), want.Keys())
+			}
+			for _, k := range want.Keys() {
+				g, _ := got.Get(k)
+				w, _ := want.Get(k)
+				if len(g) != len(w) {
+					t.Errorf("Get(%s) = %v, want %v", k, g, w)
+				}
+			}
+			parsed, err := NewParser(OptionDedupe()).Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			if parsed.String() != tt.want {
+				t.Errorf("Parse() with OptionDedupe = %v, want %v", parsed, tt.want)
+			}
+		})
+	}
+}
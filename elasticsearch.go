@@ -0,0 +1,144 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// An ElasticsearchOption can be passed to ToElasticsearch.
+type ElasticsearchOption interface {
+	Apply(cfg *esConfig)
+}
+
+type esConfig struct {
+	analyzed map[string]bool
+}
+
+// newESConfig creates a configuration from the options.
+func newESConfig(opts []ElasticsearchOption) *esConfig {
+	cfg := &esConfig{analyzed: make(map[string]bool)}
+	for _, opt := range opts {
+		opt.Apply(cfg)
+	}
+	return cfg
+}
+
+type esOptionAnalyzed []string
+
+func (o esOptionAnalyzed) Apply(cfg *esConfig) {
+	for _, k := range o {
+		cfg.analyzed[k] = true
+	}
+}
+
+// ElasticsearchOptionAnalyzed will make ToElasticsearch use match queries
+// instead of term queries for the keys, which are analyzed text fields.
+func ElasticsearchOptionAnalyzed(keys ...string) ElasticsearchOption {
+	return esOptionAnalyzed(keys)
+}
+
+// esRanges maps the ordering operators to range query parameters.
+var esRanges = map[string]string{
+	"<": "lt", ">": "gt", "<=": "lte", ">=": "gte",
+}
+
+// ToElasticsearch translates the filter into an Elasticsearch bool query,
+// which can be encoded with json.Marshal. Conditions with '=' become term
+// queries (or match queries, see ElasticsearchOptionAnalyzed), those with
+// '!=' negated term queries and those with ordering operators range queries.
+// The has operator ':' becomes an exists query for the value '*' and a match
+// query otherwise. Like Matches, '=', '!=' and ':' treat other values with
+// '*' or '?' wildcards as patterns, which become wildcard queries. Keys are
+// used as field names. The OR groups are put in the
+// filter clause (or, for a single '!=' condition, in must_not), with groups
+// of more than one condition as should clauses with a minimum_should_match
+// of 1. An empty filter results in a match_all query. For other operators, an
+// error is returned.
+func ToElasticsearch(f Filter, opts ...ElasticsearchOption) (map[string]any, error) {
+	cfg := newESConfig(opts)
+	var filter, mustNot []any
+	for _, g := range orGroups(f) {
+		if len(g) == 1 && g[0].Op() == "!=" {
+			q, err := cfg.query(g[0])
+			if err != nil {
+				return nil, err
+			}
+			mustNot = append(mustNot, q)
+			continue
+		}
+		var should []any
+		for _, c := range g {
+			q, err := cfg.clause(c)
+			if err != nil {
+				return nil, err
+			}
+			should = append(should, q)
+		}
+		if len(should) == 1 {
+			filter = append(filter, should[0])
+		} else {
+			filter = append(filter, map[string]any{
+				"bool": map[string]any{"should": should, "minimum_should_match": 1},
+			})
+		}
+	}
+	if filter == nil && mustNot == nil {
+		return map[string]any{"match_all": map[string]any{}}, nil
+	}
+	b := make(map[string]any)
+	if filter != nil {
+		b["filter"] = filter
+	}
+	if mustNot != nil {
+		b["must_not"] = mustNot
+	}
+	return map[string]any{"bool": b}, nil
+}
+
+// clause translates the condition into a query, negating it for '!='.
+func (cfg *esConfig) clause(c Condition) (map[string]any, error) {
+	q, err := cfg.query(c)
+	if err != nil || c.Op() != "!=" {
+		return q, err
+	}
+	return map[string]any{"bool": map[string]any{"must_not": []any{q}}}, nil
+}
+
+// query translates the condition into a query, ignoring negation.
+func (cfg *esConfig) query(c Condition) (map[string]any, error) {
+	k, v := c.Key(), c.StringValue()
+	switch c.Op() {
+	case ":", "=", "!=":
+		if c.Op() == ":" && v == "*" && !c.IsQuoted() {
+			return map[string]any{"exists": map[string]any{"field": k}}, nil
+		}
+		if ts, ok := parseGlob(v); ok {
+			return map[string]any{"wildcard": map[string]any{k: esWildcard(ts)}}, nil
+		}
+		if c.Op() == ":" || cfg.analyzed[k] {
+			return map[string]any{"match": map[string]any{k: v}}, nil
+		}
+		return map[string]any{"term": map[string]any{k: v}}, nil
+	}
+	if r, ok := esRanges[c.Op()]; ok {
+		return map[string]any{"range": map[string]any{k: map[string]any{r: v}}}, nil
+	}
+	return nil, fmt.Errorf("%s: unsupported operator %s", k, c.Op())
+}
+
+// esWildcard formats the glob pattern as the value of a wildcard query, in
+// which a backslash escapes the wildcards.
+func esWildcard(ts []globToken) string {
+	sb := strings.Builder{}
+	for _, t := range ts {
+		if !t.wildcard && (t.r == globAny || t.r == globOne || t.r == escapeCharacter) {
+			sb.WriteRune(escapeCharacter)
+		}
+		sb.WriteRune(t.r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,76 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestToElasticsearch(t *testing.T) {
+	p := NewParser(OptionOperators("<", ">", "<=", ">=", ":", "~"))
+	tests := []struct {
+		name    string
+		query   string
+		opts    []ElasticsearchOption
+		want    string
+		wantErr bool
+	}{
+		{"empty", "", nil, `{"match_all":{}}`, false},
+		{"term", "status=open", nil, `{"bool":{"filter":[{"term":{"status":"open"}}]}}`, false},
+		{"match", "title=hello", []ElasticsearchOption{ElasticsearchOptionAnalyzed("title")}, `{"bool":{"filter":[{"match":{"title":"hello"}}]}}`, false},
+		{"not equal", "status!=closed", nil, `{"bool":{"must_not":[{"term":{"status":"closed"}}]}}`, false},
+		{"range", "size>=10 AND size<20", nil, `{"bool":{"filter":[{"range":{"size":{"gte":"10"}}},{"range":{"size":{"lt":"20"}}}]}}`, false},
+		{"has", "tags:urgent", nil, `{"bool":{"filter":[{"match":{"tags":"urgent"}}]}}`, false},
+		{"exists", "owner:*", nil, `{"bool":{"filter":[{"exists":{"field":"owner"}}]}}`, false},
+		{"quoted star", `owner:"*"`, nil, `{"bool":{"filter":[{"wildcard":{"owner":"*"}}]}}`, false},
+		{"wildcard", "name=ba*", nil, `{"bool":{"filter":[{"wildcard":{"name":"ba*"}}]}}`, false},
+		{"wildcard, analyzed", "title=he?lo", []ElasticsearchOption{ElasticsearchOptionAnalyzed("title")}, `{"bool":{"filter":[{"wildcard":{"title":"he?lo"}}]}}`, false},
+		{"wildcard, has", "tags:urg*", nil, `{"bool":{"filter":[{"wildcard":{"tags":"urg*"}}]}}`, false},
+		{"wildcard, not equal", "name!=ba*", nil, `{"bool":{"must_not":[{"wildcard":{"name":"ba*"}}]}}`, false},
+		{"wildcard, escaped", `name="a\\b\*c*"`, nil, `{"bool":{"filter":[{"wildcard":{"name":"a\\\\b\\*c*"}}]}}`, false},
+		{"dotted key", "owner.name=joe", nil, `{"bool":{"filter":[{"term":{"owner.name":"joe"}}]}}`, false},
+		{
+			"or",
+			"status=open OR status=pending",
+			nil,
+			`{"bool":{"filter":[{"bool":{"minimum_should_match":1,"should":[{"term":{"status":"open"}},{"term":{"status":"pending"}}]}}]}}`,
+			false,
+		},
+		{
+			"mixed",
+			"created>=2024-01-01 AND status!=closed AND priority=high OR owner!=joe",
+			nil,
+			`{"bool":{"filter":[` +
+				`{"range":{"created":{"gte":"2024-01-01"}}},` +
+				`{"bool":{"minimum_should_match":1,"should":[{"term":{"priority":"high"}},{"bool":{"must_not":[{"term":{"owner":"joe"}}]}}]}}` +
+				`],"must_not":[{"term":{"status":"closed"}}]}}`,
+			false,
+		},
+		{"! unsupported operator", "title~hello", nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			q, err := ToElasticsearch(f, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToElasticsearch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got, err := json.Marshal(q)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ToElasticsearch() got = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
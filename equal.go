@@ -0,0 +1,107 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"sort"
+	"strings"
+)
+
+func (f filter) Equal(other Filter) bool {
+	if other == nil {
+		return false
+	}
+	cs, seps := conditionChain(f)
+	ocs, oseps := conditionChain(other)
+	if len(cs) != len(ocs) {
+		return false
+	}
+	for i := range cs {
+		if !sameCondition(cs[i], ocs[i]) {
+			return false
+		}
+	}
+	for i := range seps {
+		if seps[i] != oseps[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (f filter) EqualUnordered(other Filter) bool {
+	if other == nil {
+		return false
+	}
+	gs, ogs := groupKeys(f), groupKeys(other)
+	if len(gs) != len(ogs) {
+		return false
+	}
+	for i := range gs {
+		if gs[i] != ogs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// conditionChain returns the filter's conditions in order of appearance, along
+// with the separators between them.
+func conditionChain(f Filter) ([]Condition, []string) {
+	cs := f.Conditions()
+	var seps []string
+	for _, c := range cs {
+		and, or := c.AndOr()
+		if and != nil {
+			seps = append(seps, separatorAnd)
+		} else if or != nil {
+			seps = append(seps, separatorOr)
+		}
+	}
+	return cs, seps
+}
+
+// orGroups splits the filter's conditions into OR groups, which are the
+// operands of the top-level conjunction.
+func orGroups(f Filter) [][]Condition {
+	var gs [][]Condition
+	var g []Condition
+	for _, c := range f.Conditions() {
+		g = append(g, c)
+		if _, or := c.AndOr(); or == nil {
+			gs = append(gs, g)
+			g = nil
+		}
+	}
+	return gs
+}
+
+// groupKeys returns a sorted representation of the filter's OR groups, in
+// which the conditions within each group have been sorted as well.
+func groupKeys(f Filter) []string {
+	var ks []string
+	for _, g := range orGroups(f) {
+		var cks []string
+		for _, c := range g {
+			cks = append(cks, conditionKey(c))
+		}
+		sort.Strings(cks)
+		ks = append(ks, strings.Join(cks, "\x01"))
+	}
+	sort.Strings(ks)
+	return ks
+}
+
+// conditionKey returns a string that uniquely identifies the condition's key,
+// operator and value.
+func conditionKey(c Condition) string {
+	return c.Key() + "\x00" + c.Op() + "\x00" + c.StringValue()
+}
+
+// sameCondition reports whether the conditions have the same key, operator and
+// value.
+func sameCondition(a, b Condition) bool {
+	return a.Key() == b.Key() && a.Op() == b.Op() && a.StringValue() == b.StringValue()
+}
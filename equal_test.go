@@ -0,0 +1,65 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"testing"
+)
+
+func TestFilter_Equal(t *testing.T) {
+	tests := []struct {
+		name          string
+		left          string
+		right         string
+		want          bool
+		wantUnordered bool
+	}{
+		{"empty", "", "", true, true},
+		{"same", "a=1 AND b=2", "a=1 AND b=2", true, true},
+		{"whitespace", "a=1 AND b=2", "a=1   AND\tb=2", true, true},
+		{"quoting only", `a="1" AND b=2`, `a=1 AND b="2"`, true, true},
+		{"order only", "a=1 AND b=2", "b=2 AND a=1", false, true},
+		{"separator only", "a=1 AND b=2", "a=1 OR b=2", false, false},
+		{"order within OR group", "a=1 OR b=2 AND c=3", "b=2 OR a=1 AND c=3", false, true},
+		{"order of OR groups", "a=1 OR b=2 AND c=3", "c=3 AND b=2 OR a=1", false, true},
+		{"regrouped", "a=1 OR b=2 AND c=3", "a=1 AND b=2 OR c=3", false, false},
+		{"duplicates", "a=1 AND a=1", "a=1", false, false},
+		{"different value", "a=1", "a=2", false, false},
+		{"different operator", "a=1", "a!=1", false, false},
+		{"different key", "a=1", "b=1", false, false},
+		{"empty and non-empty", "", "a=1", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			left, err := NewParser().Parse(tt.left)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			right, err := NewParser().Parse(tt.right)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			if got := left.Equal(right); got != tt.want {
+				t.Errorf("Equal() = %v, want %v", got, tt.want)
+			}
+			if got := right.Equal(left); got != tt.want {
+				t.Errorf("Equal() (reversed) = %v, want %v", got, tt.want)
+			}
+			if got := left.EqualUnordered(right); got != tt.wantUnordered {
+				t.Errorf("EqualUnordered() = %v, want %v", got, tt.wantUnordered)
+			}
+			if got := right.EqualUnordered(left); got != tt.wantUnordered {
+				t.Errorf("EqualUnordered() (reversed) = %v, want %v", got, tt.wantUnordered)
+			}
+		})
+	}
+}
+
+func TestFilter_Equal_nil(t *testing.T) {
+	f, _ := NewParser().Parse("a=1")
+	if f.Equal(nil) || f.EqualUnordered(nil) {
+		t.Errorf("filter should not equal nil")
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+func (c *condition) EvaluateString(value string) (bool, error) {
+	return compareOrdered(c.op, value, c.stringValue)
+}
+
+func (c *condition) EvaluateInt(value int64) (bool, error) {
+	i, err := strconv.ParseInt(c.stringValue, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("%s is not an integer", c.stringValue)
+	}
+	return compareOrdered(c.op, value, i)
+}
+
+// compareUint applies the comparison operator to an unsigned field value and
+// a (possibly negative) integer condition value.
+func compareUint(op string, field uint64, value int64) (bool, error) {
+	if value < 0 || field > math.MaxInt64 {
+		// the field value is the greater one
+		return compareOrdered(op, 1, 0)
+	}
+	return compareOrdered(op, int64(field), value)
+}
+
+func (c *condition) EvaluateFloat(value float64) (bool, error) {
+	f, err := c.FloatValue()
+	if err != nil {
+		return false, err
+	}
+	return compareOrdered(c.op, value, f)
+}
+
+// ordered is the set of types that support the ordering operators.
+type ordered interface {
+	~string | ~int | ~int64 | ~float64
+}
+
+// compareOrdered applies the comparison operator to the field value and the
+// condition value. Strings are ordered lexicographically.
+func compareOrdered[T ordered](op string, field, value T) (bool, error) {
+	switch op {
+	case "=":
+		return field == value, nil
+	case "!=":
+		return field != value, nil
+	case "<":
+		return field < value, nil
+	case ">":
+		return field > value, nil
+	case "<=":
+		return field <= value, nil
+	case ">=":
+		return field >= value, nil
+	}
+	return false, fmt.Errorf("unsupported operator %s", op)
+}
+
+func (f filter) MatchMap(m map[string]string) (bool, error) {
+	return f.Matches(m)
+}
+
+func (f filter) MatchJSON(data []byte) (bool, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return false, fmt.Errorf("invalid JSON: %v", err)
+	}
+	return f.MatchDocument(doc)
+}
+
+// compareBools applies the (equality) operator to the field value and the
+// condition value.
+func compareBools(op string, field, value bool) (bool, error) {
+	switch op {
+	case "=":
+		return field == value, nil
+	case "!=":
+		return field != value, nil
+	}
+	return false, fmt.Errorf("unsupported operator %s for boolean", op)
+}
+
+// structTag is the struct tag that can be used to override the name by which a
+// field is matched by Apply.
+const structTag = "listfilter"
+
+func (f filter) Apply(obj interface{}) (bool, error) {
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return false, fmt.Errorf("expected a struct, got %T", obj)
+	}
+	return f.MatchStruct(obj, MatchOptionStructTag(structTag))
+}
+
+// indirect dereferences pointers until it reaches a non-pointer value. A nil
+// pointer results in an invalid Value.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
@@ -0,0 +1,382 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+type testOwner struct {
+	Name string
+}
+
+type testBase struct {
+	ID int
+}
+
+type testVersion struct {
+	major, minor int
+}
+
+func (v testVersion) String() string {
+	return fmt.Sprintf("v%d.%d", v.major, v.minor)
+}
+
+type testResource struct {
+	testBase
+	Name    string
+	Size    float64
+	Active  bool
+	Created time.Time
+	Count   uint
+	Big     uint64
+	Label   string `listfilter:"tag" json:"json"`
+	Owner   *testOwner
+	Version testVersion
+	Hidden  string `listfilter:"-"`
+	private string
+}
+
+func TestFilter_Apply(t *testing.T) {
+	obj := testResource{
+		testBase: testBase{ID: 42},
+		Name:     "foo",
+		Size:     1.5,
+		Active:   true,
+		Created:  time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC),
+		Count:    10,
+		Big:      math.MaxUint64,
+		Label:    "bar",
+		Owner:    &testOwner{Name: "bla"},
+		Version:  testVersion{1, 2},
+		Hidden:   "vla",
+		private:  "moo",
+	}
+	tests := []struct {
+		name    string
+		query   string
+		obj     interface{}
+		want    bool
+		wantErr bool
+	}{
+		{"empty", "", obj, true, false},
+		{"simple", "name=foo", obj, true, false},
+		{"no match", "name=bar", obj, false, false},
+		{"not equal", "name!=bar", obj, true, false},
+		{"pointer", "name=foo", &obj, true, false},
+		{"embedded", "id=42", obj, true, false},
+		{"integer, numeric", "id=042", obj, true, false},
+		{"! integer, not a number", "id=abc", obj, false, true},
+		{"float", "size=1.5", obj, true, false},
+		{"float, numeric", "size=1.50", obj, true, false},
+		{"bool", "active=true", obj, true, false},
+		{"bool, case-insensitive", "active=True", obj, true, false},
+		{"! bool, invalid", "active=yes", obj, false, true},
+		{"time", "created>2022-01-01", obj, true, false},
+		{"time, other format", "created=2022-03-01T13:00:00+01:00", obj, true, false},
+		{"unsigned", "count>9", obj, true, false},
+		{"unsigned, numeric", "count<9", obj, false, false},
+		{"unsigned, negative", "count>-1", obj, true, false},
+		{"unsigned, beyond int64", "big>9223372036854775807", obj, true, false},
+		{"unsigned, beyond int64, equal", "big=9223372036854775807", obj, false, false},
+		{"! unsigned, not a number", "count=abc", obj, false, true},
+		{"tag", "tag=bar", obj, true, false},
+		{"tag overrides name", "label=bar", obj, false, false},
+		{"json tag ignored", "json=bar", obj, false, false},
+		{"stringer", "version=v1.2", obj, true, false},
+		{"ignored", "hidden=vla", obj, false, false},
+		{"unexported", "private=moo", obj, false, false},
+		{"nested pointer", "owner.name=bla", obj, true, false},
+		{"nil pointer", "owner.name=bla", testResource{}, false, false},
+		{"non-existent", "foo=bar", obj, false, false},
+		{"path into non-struct", "name.foo=bar", obj, false, false},
+		{"and", "name=foo AND tag=bar", obj, true, false},
+		{"and, one fails", "name=foo AND tag=vla", obj, false, false},
+		{"or", "name=bar OR tag=bar", obj, true, false},
+		{"and before or group", "name=bar AND id=1 OR tag=bar", obj, false, false},
+		{"or group after and", "name=foo AND id=1 OR tag=bar", obj, true, false},
+		{"two or groups", "name=bar OR id=42 AND tag=vla OR active=true", obj, true, false},
+		{"! not a struct", "name=foo", "foo", false, true},
+		{"! nil", "name=foo", (*testResource)(nil), false, true},
+		{"! unsupported field", "owner=foo", obj, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewParser(OptionOperators("<", ">")).Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got, err := f.Apply(tt.obj)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Apply() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Apply() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter_MatchMap(t *testing.T) {
+	m := map[string]string{
+		"foo":     "bar",
+		"bla.vla": "moo",
+		"empty":   "",
+	}
+	tests := []struct {
+		name    string
+		query   string
+		m       map[string]string
+		want    bool
+		wantErr bool
+	}{
+		{"empty filter", "", m, true, false},
+		{"simple", "foo=bar", m, true, false},
+		{"no match", "foo=bla", m, false, false},
+		{"dotted key", "bla.vla=moo", m, true, false},
+		{"empty value", "empty=", m, true, false},
+		{"missing key", "moo=", m, false, false},
+		{"missing key, not equal", "moo!=bar", m, false, false},
+		{"nil map", "foo=bar", nil, false, false},
+		{"and", "foo=bar AND bla.vla=moo", m, true, false},
+		{"and, one fails", "foo=bar AND bla.vla=boo", m, false, false},
+		{"or", "foo=bla OR bla.vla=moo", m, true, false},
+		{"and with or group", "foo=bar AND foo=bla OR bla.vla=moo", m, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewParser().Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got, err := f.MatchMap(tt.m)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MatchMap() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("MatchMap() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter_MatchJSON(t *testing.T) {
+	doc := []byte(`{
+		"name": "foo",
+		"size": 1.5,
+		"count": 10,
+		"active": true,
+		"nothing": null,
+		"owner": {"name": "bla", "address": {"city": "vla"}},
+		"tags": ["a", "b"],
+		"scores": [1, 2, 3]
+	}`)
+	tests := []struct {
+		name    string
+		query   string
+		data    []byte
+		want    bool
+		wantErr bool
+	}{
+		{"empty filter", "", doc, true, false},
+		{"string", "name=foo", doc, true, false},
+		{"string, no match", "name=bar", doc, false, false},
+		{"float", "size=1.5", doc, true, false},
+		{"integer as float", "count=10.0", doc, true, false},
+		{"numeric, not lexicographic", "count!=9", doc, true, false},
+		{"bool", "active=true", doc, true, false},
+		{"bool, case-insensitive", "active!=FALSE", doc, true, false},
+		{"null", "nothing=", doc, false, false},
+		{"nested", "owner.name=bla", doc, true, false},
+		{"deeply nested", "owner.address.city=vla", doc, true, false},
+		{"path into non-object", "name.foo=bar", doc, false, false},
+		{"missing", "foo=bar", doc, false, false},
+		{"array", "tags=b", doc, true, false},
+		{"array, no match", "tags=c", doc, false, false},
+		{"glob", "name=f*", doc, true, false},
+		{"glob and literal", "name=fo* AND name=foo", doc, true, false},
+		{"glob, no match", "name=b?r", doc, false, false},
+		{"glob, not equal", "name!=b*", doc, true, false},
+		{"glob, escaped", `name="fo\*"`, doc, false, false},
+		{"glob, array", "tags=?", doc, true, false},
+		{"array, not equal", "tags!=a", doc, false, false},
+		{"array, not equal, no element", "tags!=c", doc, true, false},
+		{"numeric array", "scores=3", doc, true, false},
+		{"and", "name=foo AND active=true", doc, true, false},
+		{"or", "name=bar OR tags=a", doc, true, false},
+		{"! not a number", "size=large", doc, false, true},
+		{"! not a boolean", "active=yes", doc, false, true},
+		{"! object", "owner=bla", doc, false, true},
+		{"! invalid JSON", "name=foo", []byte(`{"name":`), false, true},
+		{"! not an object", "name=foo", []byte(`["foo"]`), false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewParser().Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got, err := f.MatchJSON(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MatchJSON() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("MatchJSON() got = %v, want %v", got, tt.want)
+			}
+			var m map[string]any
+			if json.Unmarshal(tt.data, &m) == nil {
+				want, err := f.MatchDocument(m)
+				if got != want || (err != nil) != tt.wantErr {
+					t.Errorf("MatchJSON() got = %v, MatchDocument() got = %v, %v", got, want, err)
+				}
+			}
+		})
+	}
+}
+
+func Test_condition_EvaluateString(t *testing.T) {
+	tests := []struct {
+		op      string
+		cond    string
+		value   string
+		want    bool
+		wantErr bool
+	}{
+		{"=", "foo", "foo", true, false},
+		{"=", "foo", "Foo", false, false},
+		{"!=", "foo", "bar", true, false},
+		{"<", "foo", "bar", true, false},
+		{"<", "bar", "foo", false, false},
+		{">", "bar", "foo", true, false},
+		{"<=", "foo", "foo", true, false},
+		{">=", "foo", "foo", true, false},
+		{">", "10", "9", true, false},
+		{"~", "foo", "foo", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value+tt.op+tt.cond, func(t *testing.T) {
+			c := NewCondition("foo", []string{"foo"}, tt.op, tt.cond)
+			got, err := c.EvaluateString(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("EvaluateString() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("EvaluateString() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_condition_EvaluateInt(t *testing.T) {
+	tests := []struct {
+		op      string
+		cond    string
+		value   int64
+		want    bool
+		wantErr bool
+	}{
+		{"=", "10", 10, true, false},
+		{"!=", "10", 10, false, false},
+		{"<", "10", 9, true, false},
+		{">", "10", 9, false, false},
+		{"<=", "10", 10, true, false},
+		{">=", "-10", -9, true, false},
+		{">", "9223372036854775806", 9223372036854775807, true, false},
+		{"=", "1.0", 1, false, true},
+		{"=", "ten", 10, false, true},
+		{"~", "10", 10, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d%s%s", tt.value, tt.op, tt.cond), func(t *testing.T) {
+			c := NewCondition("foo", []string{"foo"}, tt.op, tt.cond)
+			got, err := c.EvaluateInt(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("EvaluateInt() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("EvaluateInt() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_condition_EvaluateFloat(t *testing.T) {
+	tests := []struct {
+		op      string
+		cond    string
+		value   float64
+		want    bool
+		wantErr bool
+	}{
+		{"=", "1.5", 1.5, true, false},
+		{"=", "1", 1.0, true, false},
+		{"=", "0.1", float64(float32(0.1)), false, false},
+		{"!=", "1.5", 1.5, false, false},
+		{"<", "10", 9.99, true, false},
+		{">", "10", 9.99, false, false},
+		{"<=", "1e2", 100, true, false},
+		{">=", "-1.5", -1.5, true, false},
+		{"=", "NaN", math.NaN(), false, false},
+		{"!=", "NaN", math.NaN(), true, false},
+		{"<", "1", math.NaN(), false, false},
+		{">=", "NaN", 1, false, false},
+		{">", "1", math.Inf(1), true, false},
+		{"=", "ten", 10, false, true},
+		{"~", "10", 10, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v%s%s", tt.value, tt.op, tt.cond), func(t *testing.T) {
+			c := NewCondition("foo", []string{"foo"}, tt.op, tt.cond)
+			got, err := c.EvaluateFloat(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("EvaluateFloat() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("EvaluateFloat() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_compareOrdered(t *testing.T) {
+	tests := []struct {
+		op      string
+		field   string
+		value   string
+		want    bool
+		wantErr bool
+	}{
+		{"=", "a", "a", true, false},
+		{"!=", "a", "a", false, false},
+		{"<", "a", "b", true, false},
+		{">", "a", "b", false, false},
+		{"<=", "b", "b", true, false},
+		{">=", "b", "a", true, false},
+		{"<", "9", "10", false, false},
+		{"~", "a", "a", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field+tt.op+tt.value, func(t *testing.T) {
+			got, err := compareOrdered(tt.op, tt.field, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("compareOrdered() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("compareOrdered() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
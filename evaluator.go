@@ -0,0 +1,86 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// A FilterEvaluator evaluates a Filter against a target. Implementations can
+// add support for data models not covered by the built-in evaluators.
+type FilterEvaluator interface {
+	// Evaluate reports whether the target matches the filter. If the target is
+	// not supported by the evaluator, an error is returned.
+	Evaluate(f Filter, target interface{}) (bool, error)
+}
+
+type mapEvaluator struct{}
+
+// NewMapEvaluator returns a FilterEvaluator for map[string]string targets. See
+// Filter.Matches.
+func NewMapEvaluator() FilterEvaluator {
+	return mapEvaluator{}
+}
+
+func (mapEvaluator) Evaluate(f Filter, target interface{}) (bool, error) {
+	m, ok := target.(map[string]string)
+	if !ok {
+		return false, fmt.Errorf("expected map[string]string, got %T", target)
+	}
+	return f.Matches(m)
+}
+
+type reflectEvaluator struct{}
+
+// NewReflectEvaluator returns a FilterEvaluator for struct targets (or
+// pointers to them). See Filter.Apply.
+func NewReflectEvaluator() FilterEvaluator {
+	return reflectEvaluator{}
+}
+
+func (reflectEvaluator) Evaluate(f Filter, target interface{}) (bool, error) {
+	return f.Apply(target)
+}
+
+type jsonEvaluator struct{}
+
+// NewJSONEvaluator returns a FilterEvaluator for JSON objects, passed as
+// []byte or json.RawMessage. See Filter.MatchJSON.
+func NewJSONEvaluator() FilterEvaluator {
+	return jsonEvaluator{}
+}
+
+func (jsonEvaluator) Evaluate(f Filter, target interface{}) (bool, error) {
+	switch data := target.(type) {
+	case []byte:
+		return f.MatchJSON(data)
+	case json.RawMessage:
+		return f.MatchJSON(data)
+	}
+	return false, fmt.Errorf("expected []byte, got %T", target)
+}
+
+type defaultEvaluator struct {
+	m FilterEvaluator
+	r FilterEvaluator
+	j FilterEvaluator
+}
+
+// NewDefaultEvaluator returns a FilterEvaluator that dispatches to the map,
+// JSON or reflection-based evaluator depending on the target's type.
+func NewDefaultEvaluator() FilterEvaluator {
+	return defaultEvaluator{NewMapEvaluator(), NewReflectEvaluator(), NewJSONEvaluator()}
+}
+
+func (e defaultEvaluator) Evaluate(f Filter, target interface{}) (bool, error) {
+	switch target.(type) {
+	case map[string]string:
+		return e.m.Evaluate(f, target)
+	case []byte, json.RawMessage:
+		return e.j.Evaluate(f, target)
+	}
+	return e.r.Evaluate(f, target)
+}
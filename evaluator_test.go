@@ -0,0 +1,51 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFilterEvaluator_Evaluate(t *testing.T) {
+	type named struct {
+		Name string
+	}
+	tests := []struct {
+		name      string
+		evaluator FilterEvaluator
+		target    interface{}
+		want      bool
+		wantErr   bool
+	}{
+		{"map", NewMapEvaluator(), map[string]string{"name": "foo"}, true, false},
+		{"map, no match", NewMapEvaluator(), map[string]string{"name": "bar"}, false, false},
+		{"! map, wrong type", NewMapEvaluator(), named{"foo"}, false, true},
+		{"reflect", NewReflectEvaluator(), named{"foo"}, true, false},
+		{"reflect, pointer", NewReflectEvaluator(), &named{"foo"}, true, false},
+		{"! reflect, wrong type", NewReflectEvaluator(), []byte(`{"name":"foo"}`), false, true},
+		{"json", NewJSONEvaluator(), []byte(`{"name":"foo"}`), true, false},
+		{"json, raw message", NewJSONEvaluator(), json.RawMessage(`{"name":"foo"}`), true, false},
+		{"! json, wrong type", NewJSONEvaluator(), `{"name":"foo"}`, false, true},
+		{"default, map", NewDefaultEvaluator(), map[string]string{"name": "foo"}, true, false},
+		{"default, struct", NewDefaultEvaluator(), named{"foo"}, true, false},
+		{"default, json", NewDefaultEvaluator(), []byte(`{"name":"foo"}`), true, false},
+		{"default, json, no match", NewDefaultEvaluator(), []byte(`{"name":"bar"}`), false, false},
+		{"! default, unsupported", NewDefaultEvaluator(), 42, false, true},
+	}
+	f, _ := NewParser().Parse("name=foo")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.evaluator.Evaluate(f, tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Evaluate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Evaluate() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
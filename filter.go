@@ -36,13 +36,24 @@ The filter string should adher to the following grammar:
   EscapedChar =   '\\' | '\"' NormalChar | <not eChar>
 
 An empty string is considered a valid input and will result in an empty Filter.
+
+When a Filter is evaluated, OR binds more tightly than AND, so
+"a=1 AND b=2 OR c=3" is evaluated as "a=1 AND (b=2 OR c=3)". An empty Filter
+matches everything.
 */
 package listfilter
 
 import (
+	"encoding"
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
 	"unicode/utf8"
 )
@@ -65,6 +76,8 @@ type Condition interface {
 	Op() string
 	// StringValue returns the raw string value of the condition.
 	StringValue() string
+	// IsQuoted reports whether the value was quoted in the filter string.
+	IsQuoted() bool
 	// IntValue is a convenience function for getting a filter condition value as an
 	// integer. If the value is not an integer, an error is returned.
 	IntValue() (int, error)
@@ -75,6 +88,65 @@ type Condition interface {
 	// FloatValue is a convenience function for getting a filter condition value as
 	// a 64-bit float. If the value is not a float, an error is returned.
 	FloatValue() (float64, error)
+	// TimeValue is a convenience function for getting a filter condition value
+	// as a time. The value must be an RFC 3339 timestamp or a date
+	// (YYYY-MM-DD), which is interpreted as midnight UTC. Otherwise, an error
+	// is returned.
+	TimeValue() (time.Time, error)
+	// JSONValue is a convenience function for decoding a filter condition value
+	// as JSON into target. It follows the semantics of json.Unmarshal.
+	JSONValue(target any) error
+	// AnyValue is a convenience function for decoding a filter condition value
+	// as JSON into its generic representation (as json.Unmarshal would for an
+	// empty interface value).
+	AnyValue() (any, error)
+	// URLValue is a convenience function for getting a filter condition value as
+	// an absolute URL. The URL's scheme must be one of schemes, or when none are
+	// specified, either 'http' or 'https' (case-insensitive). If the value is not
+	// such a URL, an error is returned.
+	URLValue(schemes ...string) (*url.URL, error)
+	// RegexpValue is a convenience function for getting a filter condition
+	// value as a regular expression (see regexp.Compile). The expression is
+	// compiled once and reused on subsequent calls. If the value is not a valid
+	// expression, an error is returned.
+	RegexpValue() (*regexp.Regexp, error)
+	// SemverValue is a convenience function for getting a filter condition value
+	// as a semantic version. If the value is not a valid version, an error is
+	// returned.
+	SemverValue() (Version, error)
+	// CompareVersion compares the condition value to other as semantic versions.
+	// It returns -1, 0 or 1 when the condition value has a lower, equal or higher
+	// precedence. If either is not a valid version, an error is returned.
+	CompareVersion(other string) (int, error)
+	// EvaluateString reports whether value satisfies the condition. The
+	// operators '=' and '!=' test for (in)equality, while '<', '>', '<=' and
+	// '>=' use lexicographic ordering. For other operators, an error is
+	// returned. For numeric values, use EvaluateInt or EvaluateFloat instead.
+	EvaluateString(value string) (bool, error)
+	// EvaluateInt reports whether value satisfies the condition, comparing it
+	// numerically to the condition value. The operators '=', '!=', '<', '>',
+	// '<=' and '>=' are supported. If the condition value is not an integer or
+	// the operator is not supported, an error is returned.
+	EvaluateInt(value int64) (bool, error)
+	// EvaluateFloat reports whether value satisfies the condition, comparing it
+	// numerically to the condition value. The same operators as EvaluateInt are
+	// supported. Comparisons follow IEEE 754, so '=' requires exact equality and
+	// NaN is only unequal to anything. If the condition value is not a float or
+	// the operator is not supported, an error is returned.
+	EvaluateFloat(value float64) (bool, error)
+	// Clone returns a copy of the condition without any links to other
+	// conditions.
+	Clone() Condition
+	// WithOp returns a copy of the condition, without links, with its operator
+	// replaced by op. If op is empty, an error is returned.
+	WithOp(op string) (Condition, error)
+	// Negate returns a copy of the condition, without links, with the negated
+	// operator: '=' and '!=', '<' and '>=', and '>' and '<=' negate each other.
+	// For other operators, an error is returned.
+	Negate() (Condition, error)
+	// WithValue returns a copy of the condition, without links, with its value
+	// replaced by v.
+	WithValue(v string) Condition
 	// And returns the next AND Condition, if there is one, nil otherwise.
 	And() Condition
 	// Or returns the next OR Condition, if there is one, nil otherwise.
@@ -82,6 +154,12 @@ type Condition interface {
 	// AndOr returns the next condition in the filter. It returns a tuple; the
 	// first points to an AND condition, the second to an OR.
 	AndOr() (Condition, Condition)
+	// IsLeaf reports whether the condition has no next condition, i.e. both
+	// And and Or return nil. Conditions created with NewCondition are leaves.
+	IsLeaf() bool
+	// String returns the condition as key, operator and value, without any
+	// quoting.
+	String() string
 }
 
 type condition struct {
@@ -89,32 +167,47 @@ type condition struct {
 	keyParts    []string
 	op          string
 	stringValue string
+	quoted      bool
 	nextAnd     *condition
 	nextOr      *condition
+	// re caches the result of RegexpValue; it is created by the first call
+	// and never copied
+	re *regexpCache
+}
+
+// regexpCache holds the lazily compiled regular expression of a condition.
+type regexpCache struct {
+	once sync.Once
+	re   *regexp.Regexp
+	err  error
 }
 
 // NewCondition creates a new Condition from the specified parameters.
 func NewCondition(key string, keyParts []string, op, stringValue string) Condition {
-	return condition{key, keyParts, op, stringValue, nil, nil}
+	return &condition{key, keyParts, op, stringValue, false, nil, nil, nil}
 }
 
-func (c condition) Key() string {
+func (c *condition) Key() string {
 	return c.key
 }
 
-func (c condition) KeyParts() []string {
+func (c *condition) KeyParts() []string {
 	return c.keyParts
 }
 
-func (c condition) Op() string {
+func (c *condition) Op() string {
 	return c.op
 }
 
-func (c condition) StringValue() string {
+func (c *condition) StringValue() string {
 	return c.stringValue
 }
 
-func (c condition) IntValue() (int, error) {
+func (c *condition) IsQuoted() bool {
+	return c.quoted
+}
+
+func (c *condition) IntValue() (int, error) {
 	i, err := strconv.Atoi(c.stringValue)
 	if err != nil {
 		return 0, fmt.Errorf("%s is not an integer", c.stringValue)
@@ -122,7 +215,7 @@ func (c condition) IntValue() (int, error) {
 	return i, nil
 }
 
-func (c condition) BoolValue() (bool, error) {
+func (c *condition) BoolValue() (bool, error) {
 	switch strings.ToLower(c.stringValue) {
 	case "true":
 		return true, nil
@@ -132,7 +225,7 @@ func (c condition) BoolValue() (bool, error) {
 	return false, fmt.Errorf("%s is not a valid boolean", c.stringValue)
 }
 
-func (c condition) FloatValue() (float64, error) {
+func (c *condition) FloatValue() (float64, error) {
 	f, err := strconv.ParseFloat(c.stringValue, 64)
 	if err != nil {
 		return 0, fmt.Errorf("%s is not a valid float", c.stringValue)
@@ -140,25 +233,196 @@ func (c condition) FloatValue() (float64, error) {
 	return f, nil
 }
 
-func (c condition) And() Condition {
+func (c *condition) TimeValue() (time.Time, error) {
+	if t, ok := parseTime(c.stringValue); ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%s is not a valid timestamp", truncate(c.stringValue))
+}
+
+// parseTime parses s as an RFC 3339 timestamp or date.
+func parseTime(s string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+func (c *condition) JSONValue(target any) error {
+	if err := json.Unmarshal([]byte(c.stringValue), target); err != nil {
+		return fmt.Errorf("%s is not valid JSON: %v", truncate(c.stringValue), err)
+	}
+	return nil
+}
+
+func (c *condition) AnyValue() (any, error) {
+	var v any
+	if err := c.JSONValue(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// defaultURLSchemes are the schemes accepted by Condition.URLValue when none
+// have been specified.
+var defaultURLSchemes = []string{"http", "https"}
+
+func (c *condition) URLValue(schemes ...string) (*url.URL, error) {
+	u, err := url.Parse(c.stringValue)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid URL", truncate(c.stringValue))
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return nil, fmt.Errorf("%s is not an absolute URL", truncate(c.stringValue))
+	}
+	if len(schemes) == 0 {
+		schemes = defaultURLSchemes
+	}
+	for _, s := range schemes {
+		if strings.EqualFold(u.Scheme, s) {
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("%s has an unsupported scheme %s", truncate(c.stringValue), u.Scheme)
+}
+
+// regexpMu guards the creation of regexpCaches.
+var regexpMu sync.Mutex
+
+func (c *condition) RegexpValue() (*regexp.Regexp, error) {
+	regexpMu.Lock()
+	if c.re == nil {
+		c.re = &regexpCache{}
+	}
+	re := c.re
+	regexpMu.Unlock()
+	re.once.Do(func() {
+		re.re, re.err = compileRegexp(c.stringValue)
+	})
+	return re.re, re.err
+}
+
+func compileRegexp(s string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid regular expression: %w", truncate(s), err)
+	}
+	return re, nil
+}
+
+func (c *condition) SemverValue() (Version, error) {
+	return ParseVersion(c.stringValue)
+}
+
+func (c *condition) CompareVersion(other string) (int, error) {
+	v, err := c.SemverValue()
+	if err != nil {
+		return 0, err
+	}
+	o, err := ParseVersion(other)
+	if err != nil {
+		return 0, err
+	}
+	return v.Compare(o), nil
+}
+
+// maxQuotedLength is the maximum number of runes of a value that is included
+// in an error message.
+const maxQuotedLength = 40
+
+// truncate shortens s for use in error messages.
+func truncate(s string) string {
+	if utf8.RuneCountInString(s) <= maxQuotedLength {
+		return s
+	}
+	return string([]rune(s)[:maxQuotedLength]) + "..."
+}
+
+func (c *condition) And() Condition {
 	if c.nextAnd == (*condition)(nil) {
 		return nil
 	}
 	return c.nextAnd
 }
 
-func (c condition) Or() Condition {
+func (c *condition) Or() Condition {
 	if c.nextOr == (*condition)(nil) {
 		return nil
 	}
 	return c.nextOr
 }
 
-func (c condition) AndOr() (Condition, Condition) {
+func (c *condition) Clone() Condition {
+	parts := make([]string, len(c.keyParts))
+	copy(parts, c.keyParts)
+	return &condition{c.key, parts, c.op, c.stringValue, c.quoted, nil, nil, nil}
+}
+
+func (c *condition) WithValue(v string) Condition {
+	n := c.Clone().(*condition)
+	n.stringValue = v
+	return n
+}
+
+func (c *condition) WithOp(op string) (Condition, error) {
+	if op == "" {
+		return nil, fmt.Errorf("empty operator")
+	}
+	n := c.Clone().(*condition)
+	n.op = op
+	return n, nil
+}
+
+// negations maps operators to their negation.
+var negations = map[string]string{
+	"=":  "!=",
+	"!=": "=",
+	"<":  ">=",
+	">=": "<",
+	">":  "<=",
+	"<=": ">",
+}
+
+func (c *condition) Negate() (Condition, error) {
+	op, ok := negations[c.op]
+	if !ok {
+		return nil, fmt.Errorf("cannot negate operator %s", c.op)
+	}
+	n := c.Clone().(*condition)
+	n.op = op
+	return n, nil
+}
+
+// unlinked returns a copy of the condition without links and without its
+// cached regular expression.
+func (c *condition) unlinked() condition {
+	return condition{c.key, c.keyParts, c.op, c.stringValue, c.quoted, nil, nil, nil}
+}
+
+// next returns the next condition in the chain and the separator linking to
+// it. At the end of the chain, it returns nil and an empty string.
+func (c *condition) next() (*condition, string) {
+	if c.nextAnd != nil {
+		return c.nextAnd, separatorAnd
+	}
+	if c.nextOr != nil {
+		return c.nextOr, separatorOr
+	}
+	return nil, ""
+}
+
+func (c *condition) AndOr() (Condition, Condition) {
 	return c.And(), c.Or()
 }
 
-func (c condition) String() string {
+func (c *condition) IsLeaf() bool {
+	return c.nextAnd == nil && c.nextOr == nil
+}
+
+func (c *condition) String() string {
 	return fmt.Sprintf("%s%s%s", c.key, c.op, c.stringValue)
 }
 
@@ -203,21 +467,38 @@ func (pe *parseError) Error() string {
 
 // A Filter is a container for filter conditions as parsed by the Parser.
 type Filter interface {
-	// Get retrieves the conditions for a given key.
+	// Get retrieves the conditions for a given key. These are the same
+	// conditions as those reachable from First, so their links can be followed
+	// to the rest of the filter.
 	Get(k string) ([]Condition, bool)
 	// GetFirst retrieves the first condition for a given key.
 	GetFirst(k string) (Condition, bool)
 	// GetLast retrieves the last condition for a given key.
 	GetLast(k string) (Condition, bool)
-	// Keys returns all Condition keys found in the filter.
+	// GetPrefix retrieves the conditions whose key starts with the given dotted
+	// prefix, in order of appearance. Only whole key parts are matched: "labels"
+	// matches "labels" and "labels.x", but not "labelsx". A trailing dot in the
+	// prefix is ignored.
+	GetPrefix(prefix string) []Condition
+	// HasPrefix reports whether there are any conditions whose key starts with
+	// the given dotted prefix. See GetPrefix.
+	HasPrefix(prefix string) bool
+	// Keys returns all Condition keys found in the filter, in the order in which
+	// they first appear in the filter string.
 	Keys() []string
-	// Values returns every Condition found in the filter. Other than that
-	// conditions are grouped in blocks with the same key, there are no guarantees
-	// on ordering. If for instance insertion order is required, use Conditions.
+	// Values returns every Condition found in the filter, in order of appearance.
+	// The conditions are the same as those returned by Conditions.
+	//
+	// Deprecated: Use Conditions, or Get for the conditions of a single key.
 	Values() []Condition
 	// Len returns the number of keys in the filter. This is may be less than
-	// the total number of conditions.
+	// the total number of conditions, as returned by Size; for instance, "a=1
+	// AND a=2" has length one.
 	Len() int
+	// Size returns the total number of conditions in the filter. This may be
+	// more than the number of keys, as returned by Len; for instance, "a=1 AND
+	// a=2" has size two.
+	Size() int
 	// First returns the first condition (as encountered in the original string).
 	// Starting from this Condition and moving through its Condition.AndOr method
 	// will allow reconstruction of the original filter string.
@@ -225,31 +506,176 @@ type Filter interface {
 	// Conditions returns all conditions by order of appearance in the original
 	// filter string.
 	Conditions() []Condition
-
+	// Iterate returns an Iterator over the conditions, in order of appearance
+	// in the original filter string. The conditions are visited lazily.
+	Iterate() Iterator[Condition]
+	// Subfilter returns a new Filter containing only the conditions whose key
+	// has more key parts than, and starts with, the given dotted prefix (see
+	// GetPrefix). The prefix is stripped from their keys. When conditions are
+	// removed, the surviving neighbours are linked with AND if any of the
+	// separators between them was AND, and with OR otherwise. The original
+	// filter remains unchanged.
+	Subfilter(prefix string) Filter
+	// Without returns a new Filter without the conditions for the given keys.
+	// The remaining conditions are linked as described at Subfilter, so that
+	// removing a condition from an OR group leaves the rest of the group intact.
+	// The original filter remains unchanged.
+	Without(keys ...string) Filter
+	// Rewrite returns a new Filter by calling fn for every condition in order
+	// of appearance. If fn returns true, the condition it returns (without its
+	// links) replaces the original; if it returns false or a nil condition, the
+	// condition is removed. The remaining conditions are linked as described at
+	// Subfilter. The original filter remains unchanged.
+	Rewrite(fn func(c Condition) (Condition, bool)) Filter
+	// Dedupe returns a new Filter without duplicate conditions, keeping the
+	// first occurrence. Only duplicates within an OR group and repeated OR
+	// groups are removed; see the package documentation on precedence. The
+	// original filter remains unchanged.
+	Dedupe() Filter
+	// IsSatisfiable reports whether the filter could match anything. It is
+	// conservative and only reports false for obvious contradictions, like
+	// 'status=open AND status=closed'. See IsSatisfiableWith for filters on
+	// repeated fields.
+	IsSatisfiable() (bool, error)
+	// Stats returns a summary of the filter's structure, like the number of
+	// conditions and keys.
+	Stats() Stats
+	// Canonical returns a normalised filter string. The OR groups (see the
+	// package documentation) are ordered by their first condition, by key, then
+	// operator, then value; conditions within an OR group keep their order.
+	// Values are only quoted when necessary and separators are surrounded by
+	// single spaces. For filters without OR, filters with equal canonical forms
+	// are semantically equal.
+	Canonical() string
+	// ToAIP160 renders the filter in the form of AIP-160 (see
+	// https://google.aip.dev/160), for passing it on to APIs that follow it.
+	// Conditions with '!=' and the wildcard value '*' become negated has
+	// conditions, like "-key:*". Values are quoted unless they are numbers or
+	// plain words; values that were quoted stay quoted. Separators are upper
+	// case and surrounded by single spaces. Since OR binds tighter than AND in
+	// AIP-160 as well, no parentheses are needed. An error is returned for
+	// operators that AIP-160 does not have.
+	ToAIP160() (string, error)
+	// Clone returns a deep copy of the filter, sharing no conditions with the
+	// original.
+	Clone() Filter
+	// Equal reports whether the filters have the same conditions (key, operator
+	// and value), in the same order, linked by the same separators.
+	Equal(other Filter) bool
+	// EqualUnordered reports whether the filters are equal, disregarding the
+	// order of AND-connected OR groups and of the conditions within OR groups.
+	// For instance, "a=1 AND b=2 OR c=3" equals "c=3 OR b=2 AND a=1".
+	EqualUnordered(other Filter) bool
+	// Apply evaluates the filter against a struct (or pointer to one). It is
+	// MatchStruct, but with fields matched by their 'listfilter' struct tag
+	// (like `listfilter:"field_name"`, or "-" to ignore the field) instead of
+	// the 'json' tag. It returns an error for values other than structs.
+	Apply(obj interface{}) (bool, error)
+	// MatchMap evaluates the filter against a flat string map. It is Matches
+	// without options, so values with wildcards are glob patterns and the
+	// ordering operators compare numbers numerically.
+	//
+	// Deprecated: Use Matches.
+	MatchMap(m map[string]string) (bool, error)
+	// MatchJSON evaluates the filter against a JSON object, by decoding it
+	// and passing it to MatchDocument without options. Numbers are decoded as
+	// floats. An error is returned for invalid JSON or other values than
+	// objects.
+	MatchJSON(data []byte) (bool, error)
+	// Matches evaluates the filter against a flat string map, like labels or
+	// headers. Condition keys are looked up as-is (dotted). The operators '=',
+	// '!=' and ':' (has) compare strings; the ordering operators compare
+	// chronologically if both values are timestamps (see Condition.TimeValue),
+	// numerically if both are numbers and lexicographically otherwise.
+	// Conditions on missing keys evaluate to false, unless another MissingFieldPolicy is set.
+	Matches(m map[string]string, opts ...MatchOption) (bool, error)
+	// MatchDocument evaluates the filter against a document, like a decoded
+	// JSON object. Dotted keys are used to navigate nested maps. Slices, along
+	// the path or at its end, are flattened; a condition on them matches if any
+	// of their elements does, except for '!=', which matches if none of them
+	// equals the value. An empty slice counts as a missing field. Values are
+	// compared according to their type: numbers, booleans and times to the
+	// condition value's number, boolean or time interpretation (see
+	// Condition.TimeValue), durations to the value parsed by
+	// time.ParseDuration and strings as Matches does. If the condition value
+	// cannot be converted to the field's type, an error is returned, unless
+	// MatchOptionLenient is used. Conditions on missing or null fields
+	// evaluate to false, unless another MissingFieldPolicy is set.
+	MatchDocument(doc map[string]any, opts ...MatchOption) (bool, error)
+	// MatchStruct evaluates the filter against a struct (or pointer to one),
+	// like MatchDocument does for documents. A field matches a key part if the
+	// name in its 'json' struct tag (see MatchOptionStructTag) does or, without
+	// one, if its name does (case-insensitive). Pointers are dereferenced and
+	// nested structs, maps and fields of embedded structs can be navigated.
+	// Supported field types are strings, booleans, numbers, time.Time and
+	// slices of those; values implementing fmt.Stringer are compared as
+	// strings. Conditions on fields of other types return an error. Conditions
+	// on missing fields or
+	// nil pointers evaluate to false, unless another MissingFieldPolicy is set.
+	MatchStruct(v any, opts ...MatchOption) (bool, error)
+	// MatchFunc evaluates the filter against a record that is navigated by
+	// resolve, which returns the field value for a condition, or nil if the
+	// field does not exist. This allows matching data models the Filter does
+	// not know about, with the same semantics as MatchDocument.
+	MatchFunc(resolve func(c Condition) (any, error), opts ...MatchOption) (bool, error)
+	// Compile prepares the filter for matching many records (see Matcher). The
+	// supported operators are '=', '!=', '<', '>', '<=', '>=', ':' (has),
+	// which compares like '=' and thus checks whether a repeated field contains
+	// the value, and '~' and '!~', which match strings against the value as a
+	// regular expression (see Condition.RegexpValue). For other operators and
+	// invalid regular expressions, an error is returned, as it is for values
+	// that do not suit the types declared with MatchOptionSchema.
+	Compile(opts ...MatchOption) (*Matcher, error)
+
+	// MarshalJSON encodes the filter as an array of conditions in order of
+	// appearance. See FilterFromJSON for the reverse.
+	json.Marshaler
+	// MarshalText encodes the filter as its filter string. See Text for
+	// decoding.
+	encoding.TextMarshaler
+
+	// String returns the filter string. Whitespace is normalised and values
+	// are (re-)quoted where needed, so that parsing the result (with the same
+	// parser options) results in an equal Filter.
 	fmt.Stringer
 }
 
 type filter struct {
 	m     map[string][]Condition
+	keys  []string
 	first *condition
+	// ops holds the operators of the parser, nil means the default set
+	ops map[string]bool
+	// matcher holds the Matcher without options; nil for filters that are
+	// compiled on every use
+	matcher *matcherCache
+}
+
+// add adds the condition to the key map, keeping track of the order in which
+// keys are first encountered.
+func (f *filter) add(c Condition) {
+	k := c.Key()
+	if _, ok := f.m[k]; !ok {
+		f.keys = append(f.keys, k)
+	}
+	f.m[k] = append(f.m[k], c)
 }
 
 func (f filter) Keys() []string {
+	if f.keys != nil || len(f.m) == 0 {
+		return f.keys
+	}
+	// fallback for filters created without add
 	var ks []string
 	for k := range f.m {
 		ks = append(ks, k)
 	}
+	sort.Strings(ks)
 	return ks
 }
 
 func (f filter) Values() []Condition {
-	var ys []Condition
-	for _, xs := range f.m {
-		for _, x := range xs {
-			ys = append(ys, x)
-		}
-	}
-	return ys
+	return f.Conditions()
 }
 
 func (f filter) Get(k string) ([]Condition, bool) {
@@ -271,14 +697,132 @@ func (f filter) GetLast(k string) (Condition, bool) {
 	return nil, false
 }
 
+func (f filter) GetPrefix(prefix string) []Condition {
+	var cs []Condition
+	for _, c := range f.Conditions() {
+		if hasKeyPrefix(c.Key(), prefix) {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+func (f filter) HasPrefix(prefix string) bool {
+	for _, k := range f.Keys() {
+		if hasKeyPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasKeyPrefix reports whether the key starts with the key parts of prefix.
+func hasKeyPrefix(key, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, string(nameSeparator))
+	if prefix == "" {
+		return true
+	}
+	return key == prefix || strings.HasPrefix(key, prefix+string(nameSeparator))
+}
+
+func (f filter) Subfilter(prefix string) Filter {
+	var parts []string
+	if p := strings.TrimSuffix(prefix, string(nameSeparator)); p != "" {
+		parts = strings.Split(p, string(nameSeparator))
+	}
+	return f.rewrite(func(c condition) (condition, bool) {
+		if len(c.keyParts) <= len(parts) || !hasKeyPrefix(c.key, prefix) {
+			return condition{}, false
+		}
+		ps := make([]string, len(c.keyParts)-len(parts))
+		copy(ps, c.keyParts[len(parts):])
+		c.key, c.keyParts = strings.Join(ps, string(nameSeparator)), ps
+		return c, true
+	})
+}
+
+func (f filter) Clone() Filter {
+	return f.rewrite(func(c condition) (condition, bool) {
+		return *c.Clone().(*condition), true
+	})
+}
+
+func (f filter) Without(keys ...string) Filter {
+	drop := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		drop[k] = true
+	}
+	return f.rewrite(func(c condition) (condition, bool) {
+		return c, !drop[c.key]
+	})
+}
+
+func (f filter) Rewrite(fn func(c Condition) (Condition, bool)) Filter {
+	return f.rewrite(func(c condition) (condition, bool) {
+		r, ok := fn(&c)
+		if !ok || r == nil {
+			return condition{}, false
+		}
+		return toCondition(r), true
+	})
+}
+
+// rewrite creates a new filter by applying fn to every condition in order of
+// appearance, keeping the condition it returns, unless fn returns false. If
+// conditions are dropped, the surviving neighbours are linked with AND if any
+// of the separators between them was AND, and with OR otherwise. As OR binds
+// more tightly than AND, this keeps the groups of the remaining conditions
+// intact.
+func (f filter) rewrite(fn func(c condition) (condition, bool)) filter {
+	var cs []condition
+	var seps []string
+	sep := ""
+	for c := f.first; c != nil; {
+		next, s := c.next()
+		if r, ok := fn(c.unlinked()); ok {
+			if len(cs) > 0 {
+				seps = append(seps, sep)
+			}
+			cs = append(cs, r)
+			sep = s
+		} else if sep != separatorAnd {
+			sep = s
+		}
+		c = next
+	}
+	nf := newFilter(cs, seps)
+	nf.ops = f.ops
+	return nf
+}
+
 func (f filter) Len() int {
 	return len(f.m)
 }
 
+func (f filter) Size() int {
+	n := 0
+	for c := f.first; c != nil; c, _ = c.next() {
+		n += 1
+	}
+	return n
+}
+
 func (f filter) First() Condition {
 	return f.first
 }
 
+func (f filter) Iterate() Iterator[Condition] {
+	c := f.first
+	return iteratorFunc[Condition](func() (Condition, error) {
+		if c == nil {
+			return nil, Done
+		}
+		cur := c
+		c, _ = c.next()
+		return cur, nil
+	})
+}
+
 func (f filter) Conditions() []Condition {
 	c := f.First()
 	if c == (*condition)(nil) {
@@ -306,7 +850,7 @@ func (f filter) String() string {
 		return b.String()
 	}
 	for {
-		b.WriteString(c.(*condition).String())
+		b.WriteString(f.formatCondition(c.(*condition)))
 		and, or := c.AndOr()
 		if and != nil {
 			b.WriteString(" " + separatorAnd + " ")
@@ -325,11 +869,18 @@ type parser struct {
 	ops       map[string]bool
 	snakeCase bool
 	camelCase bool
+	dedupe    bool
 }
 
+// defaultOperators are the operators recognised by every parser.
+var defaultOperators = map[string]bool{"=": true, "!=": true}
+
 // NewParser creates a new Parser.
 func NewParser(options ...Option) Parser {
-	f := &parser{ops: map[string]bool{"=": true, "!=": true}}
+	f := &parser{ops: make(map[string]bool)}
+	for op := range defaultOperators {
+		f.ops[op] = true
+	}
 	for _, opt := range options {
 		opt.Apply(f)
 	}
@@ -349,6 +900,10 @@ func (p *parser) Parse(s string) (Filter, error) {
 	if err != nil {
 		return nil, err
 	}
+	f.ops = p.ops
+	if p.dedupe {
+		f = f.dedupe()
+	}
 	return f, nil
 }
 
@@ -364,7 +919,7 @@ const (
 )
 
 func (p *parser) parseConditions(s string, start int) (filter, int, error) {
-	f := filter{m: make(map[string][]Condition)}
+	f := filter{m: make(map[string][]Condition), matcher: &matcherCache{}}
 	first, i, err := p.parseCondition(s, start)
 	if err != nil {
 		return emptyFilter, i, err
@@ -387,13 +942,41 @@ func (p *parser) parseConditions(s string, start int) (filter, int, error) {
 		} else {
 			prev.nextOr = &cond
 		}
-		f.m[prev.key] = append(f.m[prev.key], *prev)
+		f.add(prev)
 		prev = &cond
 	}
-	f.m[prev.key] = append(f.m[prev.key], *prev)
+	f.add(prev)
 	return f, start, nil
 }
 
+// newFilter creates a filter from the conditions, linking each condition to
+// the next using the separator at the same index. Any links already present on
+// the conditions are discarded.
+func newFilter(cs []condition, seps []string) filter {
+	f := filter{m: make(map[string][]Condition)}
+	if len(cs) == 0 {
+		return f
+	}
+	f.matcher = &matcherCache{}
+	nodes := make([]condition, len(cs))
+	copy(nodes, cs)
+	for i := range nodes {
+		nodes[i].nextAnd, nodes[i].nextOr = nil, nil
+	}
+	for i := 0; i < len(nodes)-1; i += 1 {
+		if seps[i] == separatorAnd {
+			nodes[i].nextAnd = &nodes[i+1]
+		} else {
+			nodes[i].nextOr = &nodes[i+1]
+		}
+	}
+	f.first = &nodes[0]
+	for i := range nodes {
+		f.add(&nodes[i])
+	}
+	return f
+}
+
 func spaceOrNonSpace(s string, start int, space bool) int {
 	i := start
 	for i < len(s) {
@@ -432,11 +1015,12 @@ func (p *parser) parseCondition(s string, start int) (condition, int, error) {
 	if err != nil {
 		return condition{}, i, err
 	}
+	quoted := i < len(s) && s[i] == quote
 	value, i, err := p.parseValue(s, i)
 	if err != nil {
 		return condition{}, i, err
 	}
-	return condition{key, keyParts, op, value, nil, nil}, i, nil
+	return condition{key, keyParts, op, value, quoted, nil, nil, nil}, i, nil
 }
 
 func (p *parser) parseFullName(s string, start int) (string, []string, int, error) {
@@ -494,14 +1078,17 @@ func (p *parser) parseName(s string, start int) (string, int, error) {
 }
 
 func (p *parser) parseOperator(s string, start int) (string, int, error) {
-	i := start
-	for i < len(s) {
-		i += 1
-		if v := s[start:i]; p.ops[v] {
-			return v, i, nil
+	// prefer the longest operator, so that '<=' is not read as '<'
+	op := ""
+	for o := range p.ops {
+		if len(o) > len(op) && strings.HasPrefix(s[start:], o) {
+			op = o
 		}
 	}
-	return "", i, newParseError("expected operator", start, s[start:])
+	if op == "" {
+		return "", len(s), newParseError("expected operator", start, s[start:])
+	}
+	return op, start + len(op), nil
 }
 
 func (p *parser) parseValue(s string, start int) (string, int, error) {
@@ -580,6 +1167,21 @@ func OptionSnakeCase() Option {
 	return &optionSnakeCase{}
 }
 
+type optionOperators []string
+
+func (o optionOperators) Apply(parser *parser) {
+	for _, op := range o {
+		parser.ops[op] = true
+	}
+}
+
+// OptionOperators will register additional operators with the parser. By
+// default, only '=' and '!=' are recognised. When operators share a prefix
+// (like '<' and '<='), the longest matching operator is used.
+func OptionOperators(ops ...string) Option {
+	return optionOperators(ops)
+}
+
 type optionCamelCase struct{}
 
 func (o optionCamelCase) Apply(parser *parser) {
@@ -593,6 +1195,18 @@ func OptionCamelCase() Option {
 	return &optionCamelCase{}
 }
 
+type optionDedupe struct{}
+
+func (o optionDedupe) Apply(parser *parser) {
+	parser.dedupe = true
+}
+
+// OptionDedupe will instruct the parser to remove duplicate conditions, as
+// Filter.Dedupe does.
+func OptionDedupe() Option {
+	return &optionDedupe{}
+}
+
 func snakeCase(s string) string {
 	sb := strings.Builder{}
 	underscore := true
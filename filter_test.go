@@ -9,6 +9,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 	"unicode"
 )
 
@@ -133,6 +134,18 @@ func Test_filterParser_Parse(t *testing.T) {
 			map[string][]Condition{"foo": {NewCondition("foo", []string{"foo"}, "=", "=")}},
 			nil,
 		},
+		{
+			"longest operator",
+			fields{ops: NewParser(OptionOperators("<", "<=")).(*parser).ops},
+			args{s: "foo<=bar AND bla<vla"},
+			func() map[string][]Condition {
+				return map[string][]Condition{
+					"foo": {&condition{"foo", []string{"foo"}, "<=", "bar", false, dummy, nil, nil}},
+					"bla": {&condition{"bla", []string{"bla"}, "<", "vla", false, nil, nil, nil}},
+				}
+			}(),
+			nil,
+		},
 		{
 			"! unknown operator",
 			standardFields,
@@ -146,9 +159,9 @@ func Test_filterParser_Parse(t *testing.T) {
 			args{s: "foo=bar AND\n\tbla=vla   AND moo=boo"},
 			func() map[string][]Condition {
 				return map[string][]Condition{
-					"foo": {condition{"foo", []string{"foo"}, "=", "bar", dummy, nil}},
-					"bla": {condition{"bla", []string{"bla"}, "=", "vla", dummy, nil}},
-					"moo": {condition{"moo", []string{"moo"}, "=", "boo", nil, nil}},
+					"foo": {&condition{"foo", []string{"foo"}, "=", "bar", false, dummy, nil, nil}},
+					"bla": {&condition{"bla", []string{"bla"}, "=", "vla", false, dummy, nil, nil}},
+					"moo": {&condition{"moo", []string{"moo"}, "=", "boo", false, nil, nil, nil}},
 				}
 			}(),
 			nil,
@@ -159,9 +172,9 @@ func Test_filterParser_Parse(t *testing.T) {
 			args{s: "foo=bar AND\n\tbla=vla   OR moo=boo"},
 			func() map[string][]Condition {
 				return map[string][]Condition{
-					"foo": {condition{"foo", []string{"foo"}, "=", "bar", dummy, nil}},
-					"bla": {condition{"bla", []string{"bla"}, "=", "vla", nil, dummy}},
-					"moo": {condition{"moo", []string{"moo"}, "=", "boo", nil, nil}},
+					"foo": {&condition{"foo", []string{"foo"}, "=", "bar", false, dummy, nil, nil}},
+					"bla": {&condition{"bla", []string{"bla"}, "=", "vla", false, nil, dummy, nil}},
+					"moo": {&condition{"moo", []string{"moo"}, "=", "boo", false, nil, nil, nil}},
 				}
 			}(),
 			nil,
@@ -172,9 +185,9 @@ func Test_filterParser_Parse(t *testing.T) {
 			args{s: "fooBar=fooBar AND\n\tblaVla=bla_vla   AND mo_O=boo"},
 			func() map[string][]Condition {
 				return map[string][]Condition{
-					"foo_bar": {condition{"foo_bar", []string{"foo_bar"}, "=", "fooBar", dummy, nil}},
-					"bla_vla": {condition{"bla_vla", []string{"bla_vla"}, "=", "bla_vla", dummy, nil}},
-					"mo_o":    {condition{"mo_o", []string{"mo_o"}, "=", "boo", nil, nil}},
+					"foo_bar": {&condition{"foo_bar", []string{"foo_bar"}, "=", "fooBar", false, dummy, nil, nil}},
+					"bla_vla": {&condition{"bla_vla", []string{"bla_vla"}, "=", "bla_vla", false, dummy, nil, nil}},
+					"mo_o":    {&condition{"mo_o", []string{"mo_o"}, "=", "boo", false, nil, nil, nil}},
 				}
 			}(),
 			nil,
@@ -186,9 +199,9 @@ func Test_filterParser_Parse(t *testing.T) {
 			func() map[string][]Condition {
 				dummy := &condition{}
 				return map[string][]Condition{
-					"fooBar": {condition{"fooBar", []string{"fooBar"}, "=", "foo_Bar", dummy, nil}},
-					"blaVla": {condition{"blaVla", []string{"blaVla"}, "=", "bla_vla", dummy, nil}},
-					"moO":    {condition{"moO", []string{"moO"}, "=", "boo", nil, nil}},
+					"fooBar": {&condition{"fooBar", []string{"fooBar"}, "=", "foo_Bar", false, dummy, nil, nil}},
+					"blaVla": {&condition{"blaVla", []string{"blaVla"}, "=", "bla_vla", false, dummy, nil, nil}},
+					"moO":    {&condition{"moO", []string{"moO"}, "=", "boo", false, nil, nil, nil}},
 				}
 			}(),
 			nil,
@@ -337,8 +350,8 @@ func Test_filterParser_Parse(t *testing.T) {
 			for _, k := range got.Keys() {
 				vs, _ := got.Get(k)
 				for i, v := range vs {
-					want := tt.want[k][i].(condition)
-					if !conditionsEqual(v.(condition), want) {
+					want := tt.want[k][i].(*condition)
+					if !conditionsEqual(v, want) {
 						t.Errorf("\nExpected: %s,\ngot:      %s", want, v)
 					}
 				}
@@ -347,6 +360,39 @@ func Test_filterParser_Parse(t *testing.T) {
 	}
 }
 
+func TestFilter_GetFirst_sameNode(t *testing.T) {
+	f, err := NewParser().Parse("foo=bar AND bla=vla OR moo=mii AND foo=boo")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	first, _ := f.GetFirst("foo")
+	if first != f.First() {
+		t.Errorf("GetFirst() got = %p, want %p", first, f.First())
+	}
+	bla, _ := f.GetFirst("bla")
+	if first.And() != bla {
+		t.Errorf("GetFirst().And() got = %p, want %p", first.And(), bla)
+	}
+	moo, _ := f.GetFirst("moo")
+	if bla.Or() != moo {
+		t.Errorf("Or() got = %p, want %p", bla.Or(), moo)
+	}
+	last, _ := f.GetLast("foo")
+	if moo.And() != last {
+		t.Errorf("And() got = %p, want %p", moo.And(), last)
+	}
+	for i, c := range f.Conditions() {
+		cs, _ := f.Get(c.Key())
+		found := false
+		for _, x := range cs {
+			found = found || x == c
+		}
+		if !found {
+			t.Errorf("condition %d (%v) not in Get(%s)", i, c, c.Key())
+		}
+	}
+}
+
 type filterFields struct {
 	m     map[string][]Condition
 	first *condition
@@ -355,7 +401,7 @@ type filterFields struct {
 func createCondition(i int) condition {
 	key := fmt.Sprintf("key%d", i)
 	val := fmt.Sprintf("val%d", i)
-	return condition{key, []string{key}, "=", val, nil, nil}
+	return condition{key, []string{key}, "=", val, false, nil, nil, nil}
 }
 
 func createFields(n int, or ...int) filterFields {
@@ -426,8 +472,8 @@ func TestFilter_Conditions(t *testing.T) {
 			got := f.Conditions()
 			i := 0
 			for ; i < len(got) && i < len(tt.want); i += 1 {
-				if !conditionsEqual(got[i], tt.want[i]) {
-					t.Errorf("\nExpected: %s,\ngot:      %v", tt.want[i], got[i])
+				if !conditionsEqual(got[i], &tt.want[i]) {
+					t.Errorf("\nExpected: %s,\ngot:      %v", &tt.want[i], got[i])
 					return
 				}
 			}
@@ -453,7 +499,7 @@ func TestCondition_AndOr(t *testing.T) {
 	}
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
-			f := filter{tt.fields.m, tt.fields.first}
+			f := filter{m: tt.fields.m, first: tt.fields.first}
 			c := f.First()
 			if c == (*condition)(nil) {
 				if len(tt.want) != 0 {
@@ -477,14 +523,440 @@ func TestCondition_AndOr(t *testing.T) {
 				break
 			}
 			for i := range cs {
-				if !conditionsEqual(cs[i], tt.want[i]) {
-					t.Errorf("\nExpected: %s,\ngot:      %v", tt.want, cs)
+				if !conditionsEqual(cs[i], &tt.want[i]) {
+					t.Errorf("\nExpected: %v,\ngot:      %v", tt.want, cs)
 				}
 			}
 		})
 	}
 }
 
+func TestCondition_IsLeaf(t *testing.T) {
+	if c := NewCondition("foo", []string{"foo"}, "=", "bar"); !c.IsLeaf() {
+		t.Errorf("IsLeaf() got = false, want true for %v", c)
+	}
+	f, err := NewParser().Parse("foo=bar AND bla=vla OR moo=mii")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cs := f.Conditions()
+	for i, c := range cs {
+		want := i == len(cs)-1
+		if got := c.IsLeaf(); got != want {
+			t.Errorf("IsLeaf() got = %v, want %v for %v", got, want, c)
+		}
+	}
+}
+
+func TestCondition_String(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Condition
+		want string
+	}{
+		{"simple", NewCondition("foo", []string{"foo"}, "=", "bar"), "foo=bar"},
+		{"dotted", NewCondition("foo.bar", []string{"foo", "bar"}, "!=", "1"), "foo.bar!=1"},
+		{"pointer", &condition{"foo", []string{"foo"}, "=", "bar moo", true, nil, nil, nil}, "foo=bar moo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fmt.Sprintf("%v", tt.c); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter_Size(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    int
+		wantLen int
+	}{
+		{"empty", "", 0, 0},
+		{"single", "foo=bar", 1, 1},
+		{"duplicate key", "foo=bar AND foo!=bla", 2, 1},
+		{"or", "foo=bar OR bla=vla", 2, 2},
+		{"mixed", "foo=bar OR foo=bla AND moo=boo OR foo=vla", 4, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewParser().Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			if got := f.Size(); got != tt.want {
+				t.Errorf("Size() = %v, want %v", got, tt.want)
+			}
+			if got := f.Len(); got != tt.wantLen {
+				t.Errorf("Len() = %v, want %v", got, tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestFilter_Size_manual(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields filterFields
+		want   int
+	}{
+		{"empty", createFields(0), 0},
+		{"single", createFields(1), 1},
+		{"triple with OR", createFields(3, 2), 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := filter{m: tt.fields.m, first: tt.fields.first}
+			if got := f.Size(); got != tt.want {
+				t.Errorf("Size() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter_Keys(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"empty", "", nil},
+		{"single", "foo=bar", []string{"foo"}},
+		{"five keys", "e=1 AND b=2 OR d=3 AND a=4 AND c=5", []string{"e", "b", "d", "a", "c"}},
+		{"duplicate keys", "b=1 AND a=2 AND b=3", []string{"b", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i += 1 {
+				f, err := NewParser().Parse(tt.query)
+				if err != nil {
+					t.Fatalf("unexpected parse error: %v", err)
+				}
+				if got := f.Keys(); !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("Keys() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFilter_Values(t *testing.T) {
+	f, _ := NewParser().Parse("b=1 AND a=2 OR b=3 AND c=4 OR a=5")
+	got := f.Values()
+	want := f.Conditions()
+	if len(got) != len(want) {
+		t.Fatalf("Values() = %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] || !conditionsEqual(got[i], want[i]) {
+			t.Errorf("Values()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+	var ss []string
+	for _, c := range got {
+		ss = append(ss, fmt.Sprint(c))
+	}
+	if s := strings.Join(ss, ","); s != "b=1,a=2,b=3,c=4,a=5" {
+		t.Errorf("Values() = %v, want appearance order", s)
+	}
+}
+
+func TestFilter_Iterate(t *testing.T) {
+	f, err := NewParser().Parse("foo=bar AND bla=vla OR moo=mii")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	it := f.Iterate()
+	for _, want := range []string{"foo=bar", "bla=vla", "moo=mii"} {
+		c, err := it.Next()
+		if err != nil {
+			t.Fatalf("Next() unexpected error: %v", err)
+		}
+		if c.String() != want {
+			t.Errorf("Next() got = %v, want %v", c, want)
+		}
+	}
+	for i := 0; i < 2; i += 1 {
+		if c, err := it.Next(); err != Done || c != nil {
+			t.Errorf("Next() after end got = %v, %v, want Done", c, err)
+		}
+	}
+	if _, err := emptyFilter.Iterate().Next(); err != Done {
+		t.Errorf("Next() on empty filter got = %v, want Done", err)
+	}
+}
+
+func TestFilter_GetPrefix(t *testing.T) {
+	query := "labels.env=prod AND labelsx=1 AND name=foo OR labels.tier=web AND labels=2 AND lab.x=3"
+	tests := []struct {
+		name   string
+		prefix string
+		want   []string
+	}{
+		{"segment prefix", "labels", []string{"labels.env=prod", "labels.tier=web", "labels=2"}},
+		{"trailing dot", "labels.", []string{"labels.env=prod", "labels.tier=web", "labels=2"}},
+		{"full key", "labels.env", []string{"labels.env=prod"}},
+		{"partial segment", "lab", []string{"lab.x=3"}},
+		{"textual prefix only", "labelsx.", []string{"labelsx=1"}},
+		{"no match", "label", nil},
+		{"empty prefix", "", []string{"labels.env=prod", "labelsx=1", "name=foo", "labels.tier=web", "labels=2", "lab.x=3"}},
+	}
+	f, err := NewParser().Parse(query)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range f.GetPrefix(tt.prefix) {
+				got = append(got, fmt.Sprint(c))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetPrefix() = %v, want %v", got, tt.want)
+			}
+			if has := f.HasPrefix(tt.prefix); has != (len(tt.want) > 0) {
+				t.Errorf("HasPrefix() = %v, want %v", has, len(tt.want) > 0)
+			}
+		})
+	}
+}
+
+func TestFilter_Subfilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		prefix string
+		want   string
+	}{
+		{"simple", "order.id=1 AND name=foo", "order", "id=1"},
+		{"trailing dot", "order.id=1 AND name=foo", "order.", "id=1"},
+		{"first removed", "name=foo AND order.id=1 OR order.ref=x", "order", "id=1 OR ref=x"},
+		{"all removed", "name=foo AND bla=vla", "order", ""},
+		{"prefix itself removed", "order=1 AND order.id=2", "order", "id=2"},
+		{"segment boundary", "orders.id=1 AND order.id=2", "order", "id=2"},
+		{"deep prefix", "a.b.c=1 AND a.b=2 AND a.b.d.e=3", "a.b", "c=1 AND d.e=3"},
+		{"and wins", "order.id=1 AND name=foo OR order.ref=x", "order", "id=1 AND ref=x"},
+		{"or kept", "order.id=1 OR name=foo OR order.ref=x", "order", "id=1 OR ref=x"},
+		{"empty prefix", "order.id=1 OR name=foo", "", "order.id=1 OR name=foo"},
+		{"empty filter", "", "order", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewParser().Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			before := f.String()
+			got := f.Subfilter(tt.prefix)
+			if got.String() != tt.want {
+				t.Errorf("Subfilter() = %v, want %v", got, tt.want)
+			}
+			if f.String() != before {
+				t.Errorf("original changed to %v, want %v", f, before)
+			}
+			want, _ := NewParser().Parse(tt.want)
+			if !reflect.DeepEqual(got.Keys(), want.Keys()) {
+				t.Errorf("Keys() = %v, want %v", got.Keys(), want.Keys())
+			}
+			gs, ws := got.Conditions(), want.Conditions()
+			if len(gs) != len(ws) {
+				t.Fatalf("Conditions() = %v, want %v", gs, ws)
+			}
+			for i := range gs {
+				if !conditionsEqual(gs[i], ws[i]) {
+					t.Errorf("Conditions()[%d] = %v, want %v", i, gs[i], ws[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFilter_Without(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		keys  []string
+		want  string
+	}{
+		{"nothing", "foo=bar AND page_size=10", nil, "foo=bar AND page_size=10"},
+		{"last", "foo=bar AND page_size=10", []string{"page_size"}, "foo=bar"},
+		{"first", "page_size=10 AND foo=bar", []string{"page_size"}, "foo=bar"},
+		{"multiple keys", "page_size=10 AND foo=bar AND order_by=foo", []string{"page_size", "order_by"}, "foo=bar"},
+		{"duplicate key", "foo=1 AND bar=2 AND foo=3", []string{"foo"}, "bar=2"},
+		{"all", "foo=1 OR foo=2", []string{"foo"}, ""},
+		{"unknown key", "foo=bar", []string{"bar"}, "foo=bar"},
+		{"middle of OR group", "a=1 OR b=2 OR c=3 AND d=4", []string{"b"}, "a=1 OR c=3 AND d=4"},
+		{"end of OR group", "a=1 OR b=2 AND c=3", []string{"b"}, "a=1 AND c=3"},
+		{"start of OR group", "a=1 AND b=2 OR c=3", []string{"b"}, "a=1 AND c=3"},
+		{"whole OR group", "a=1 AND b=2 OR b=3 AND c=4", []string{"b"}, "a=1 AND c=4"},
+		{"dotted key", "a.b=1 AND a=2", []string{"a.b"}, "a=2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewParser().Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got := f.Without(tt.keys...)
+			if got.String() != tt.want {
+				t.Errorf("Without() = %v, want %v", got, tt.want)
+			}
+			if f.String() != tt.query {
+				t.Errorf("original changed to %v, want %v", f, tt.query)
+			}
+			reparsed, err := NewParser().Parse(got.String())
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			if !reflect.DeepEqual(got.Keys(), reparsed.Keys()) {
+				t.Errorf("Keys() = %v, want %v", got.Keys(), reparsed.Keys())
+			}
+			gs, ws := got.Conditions(), reparsed.Conditions()
+			if len(gs) != len(ws) {
+				t.Fatalf("Conditions() = %v, want %v", gs, ws)
+			}
+			for i := range gs {
+				if !conditionsEqual(gs[i], ws[i]) {
+					t.Errorf("Conditions()[%d] = %v, want %v", i, gs[i], ws[i])
+				}
+			}
+			for _, k := range tt.keys {
+				if _, ok := got.Get(k); ok {
+					t.Errorf("Get(%s) should not return conditions", k)
+				}
+			}
+		})
+	}
+}
+
+func TestFilter_Rewrite(t *testing.T) {
+	upper := func(c Condition) (Condition, bool) {
+		return c.WithValue(strings.ToUpper(c.StringValue())), true
+	}
+	tests := []struct {
+		name  string
+		query string
+		fn    func(c Condition) (Condition, bool)
+		want  string
+	}{
+		{"identity", `foo=bar AND bla="vla" OR moo=mii`, func(c Condition) (Condition, bool) { return c, true }, `foo=bar AND bla="vla" OR moo=mii`},
+		{"replace all", "foo=bar AND bla=vla OR moo=mii", upper, "foo=BAR AND bla=VLA OR moo=MII"},
+		{
+			"replace first",
+			"foo=bar AND bla=vla",
+			func(c Condition) (Condition, bool) {
+				if c.Key() == "foo" {
+					return NewCondition("tenant", []string{"tenant"}, "=", "x"), true
+				}
+				return c, true
+			},
+			"tenant=x AND bla=vla",
+		},
+		{
+			"drop last",
+			"foo=bar AND bla=vla OR moo=mii",
+			func(c Condition) (Condition, bool) { return c, c.Key() != "moo" },
+			"foo=bar AND bla=vla",
+		},
+		{
+			"drop from OR group",
+			"a=1 AND b=2 OR c=3 AND d=4",
+			func(c Condition) (Condition, bool) { return c, c.Key() != "c" },
+			"a=1 AND b=2 AND d=4",
+		},
+		{"drop all", "foo=bar AND bla=vla", func(c Condition) (Condition, bool) { return nil, false }, ""},
+		{"nil replacement", "foo=bar AND bla=vla", func(c Condition) (Condition, bool) { return nil, true }, ""},
+		{"empty", "", upper, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewParser().Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got := f.Rewrite(tt.fn)
+			if got.String() != tt.want {
+				t.Errorf("Rewrite() = %v, want %v", got, tt.want)
+			}
+			if f.String() != tt.query {
+				t.Errorf("original changed to %v, want %v", f, tt.query)
+			}
+			reparsed, err := NewParser().Parse(got.String())
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			if !reflect.DeepEqual(got.Keys(), reparsed.Keys()) {
+				t.Errorf("Keys() = %v, want %v", got.Keys(), reparsed.Keys())
+			}
+			gs, ws := got.Conditions(), reparsed.Conditions()
+			if len(gs) != len(ws) {
+				t.Fatalf("Conditions() = %v, want %v", gs, ws)
+			}
+			for i := range gs {
+				if !conditionsEqual(gs[i], ws[i]) || gs[i].IsQuoted() != ws[i].IsQuoted() {
+					t.Errorf("Conditions()[%d] = %v, want %v", i, gs[i], ws[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFilter_Clone(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"empty", ""},
+		{"single", "foo.bar=1"},
+		{"mixed", "foo.bar=1 AND bla=2 OR foo.bar=3 AND moo=4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, _ := NewParser().Parse(tt.query)
+			before := f.Conditions()
+			got := f.Clone()
+			if !got.Equal(f) || got.String() != tt.query {
+				t.Errorf("Clone() = %v, want %v", got, f)
+			}
+			if !reflect.DeepEqual(got.Keys(), f.Keys()) {
+				t.Errorf("Keys() = %v, want %v", got.Keys(), f.Keys())
+			}
+			// mutate every node of the clone
+			for _, c := range got.Conditions() {
+				c := c.(*condition)
+				c.keyParts[0] = "xxx"
+				c.stringValue = "changed"
+				c.nextAnd, c.nextOr = nil, nil
+			}
+			if f.String() != tt.query {
+				t.Errorf("original changed to %v", f)
+			}
+			after := f.Conditions()
+			if len(after) != len(before) {
+				t.Fatalf("original conditions changed to %v", after)
+			}
+			for i := range after {
+				if !conditionsEqual(after[i], before[i]) || after[i].KeyParts()[0] == "xxx" {
+					t.Errorf("original condition changed to %v", after[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFilter_Clone_rewritten(t *testing.T) {
+	f, _ := NewParser().Parse("foo=1 AND bla=2 OR moo=3")
+	got := f.Clone().Without("bla").Subfilter("")
+	if got.String() != "foo=1 AND moo=3" {
+		t.Errorf("got %v", got)
+	}
+	if f.String() != "foo=1 AND bla=2 OR moo=3" {
+		t.Errorf("original changed to %v", f)
+	}
+}
+
 func BenchmarkParser_Parse(b *testing.B) {
 	p := NewParser()
 	type args struct {
@@ -727,6 +1199,337 @@ func Test_condition_BoolValue(t *testing.T) {
 	}
 }
 
+func Test_condition_TimeValue(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"2024-03-01T12:30:00Z", time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC), false},
+		{"2024-03-01T12:30:00.5+01:00", time.Date(2024, 3, 1, 11, 30, 0, 5e8, time.UTC), false},
+		{"2024-03-01", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), false},
+		{"2024-3-1", time.Time{}, true},
+		{"yesterday", time.Time{}, true},
+		{"", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			c := NewCondition("foo", []string{"foo"}, "=", tt.value)
+			got, err := c.TimeValue()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TimeValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("TimeValue() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_condition_JSONValue(t *testing.T) {
+	type target struct {
+		K int `json:"k"`
+	}
+	tests := []struct {
+		name    string
+		value   string
+		want    target
+		wantErr bool
+	}{
+		{"object", `{"k":1}`, target{K: 1}, false},
+		{"empty object", `{}`, target{}, false},
+		{"malformed", `{"k":1`, target{}, true},
+		{"wrong type", `[1]`, target{}, true},
+		{"not json", `bar`, target{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCondition("foo", []string{"foo"}, "=", tt.value)
+			var got target
+			err := c.JSONValue(&got)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("JSONValue() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("JSONValue() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_condition_AnyValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    any
+		wantErr bool
+	}{
+		{"object", `foo={"k":1}`, map[string]any{"k": 1.0}, false},
+		{"quoted object", `foo="{\"k\": \"a b\"}"`, map[string]any{"k": "a b"}, false},
+		{"array", `foo=[1,"a",true]`, []any{1.0, "a", true}, false},
+		{"number", `foo=42.5`, 42.5, false},
+		{"string", `foo="\"bar\""`, "bar", false},
+		{"boolean", `foo=true`, true, false},
+		{"null", `foo=null`, nil, false},
+		{"malformed", `foo={"k":`, nil, true},
+		{"empty", `foo=`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewParser().Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got, err := f.First().AnyValue()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AnyValue() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AnyValue() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_condition_JSONValue_errorMessage(t *testing.T) {
+	value := "{" + strings.Repeat("x", 100)
+	c := NewCondition("foo", []string{"foo"}, "=", value)
+	err := c.JSONValue(new(any))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	msg := err.Error()
+	if strings.Contains(msg, value) {
+		t.Errorf("expected value to be truncated in %q", msg)
+	}
+	if !strings.Contains(msg, "invalid character") {
+		t.Errorf("expected JSON error in %q", msg)
+	}
+}
+
+func Test_condition_URLValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		schemes []string
+		want    string
+		wantErr bool
+	}{
+		{"https", "target=https://example.com/x?y=1", nil, "https://example.com/x?y=1", false},
+		{"http", "target=http://example.com", nil, "http://example.com", false},
+		{"upper case scheme", "target=HTTPS://example.com", nil, "https://example.com", false},
+		{"quoted with spaces in query", `target="https://example.com/x?q=a b AND c"`, nil, "https://example.com/x?q=a b AND c", false},
+		{"! ftp", "target=ftp://example.com/x", nil, "", true},
+		{"ftp allowed", "target=ftp://example.com/x", []string{"ftp"}, "ftp://example.com/x", false},
+		{"! https not allowed", "target=https://example.com/x", []string{"ftp"}, "", true},
+		{"! relative path", "target=/x/y", nil, "", true},
+		{"! schemeless", "target=example.com/x", nil, "", true},
+		{"! no host", "target=https:/x", nil, "", true},
+		{"! invalid", `target="https://exa mple.com"`, nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewParser().Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got, err := f.First().URLValue(tt.schemes...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("URLValue() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("URLValue() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_condition_RegexpValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		match   string
+		want    bool
+		wantErr bool
+	}{
+		{"simple", "name=^ba.$", "bar", true, false},
+		{"no match", "name=^ba.$", "bars", false, false},
+		{"quoted", `name="^a b+$"`, "a bbb", true, false},
+		{"! invalid", "name=ba(r", "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewParser().Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got, err := f.First().RegexpValue()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RegexpValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.MatchString(tt.match) != tt.want {
+				t.Errorf("RegexpValue() = %v, MatchString(%q) want %v", got, tt.match, tt.want)
+			}
+			if again, _ := f.First().RegexpValue(); again != got {
+				t.Errorf("RegexpValue() compiled again")
+			}
+		})
+	}
+}
+
+func Test_condition_RegexpValue_withValue(t *testing.T) {
+	c := NewCondition("name", []string{"name"}, "=", "^a$")
+	if _, err := c.RegexpValue(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := c.WithValue("^b$").RegexpValue()
+	if err != nil || !got.MatchString("b") {
+		t.Errorf("RegexpValue() after WithValue got = %v, %v, want ^b$", got, err)
+	}
+}
+
+func Test_condition_RegexpValue_cache(t *testing.T) {
+	f, _ := NewParser().Parse("foo=^a$ AND bar=^b$")
+	for _, c := range f.Conditions() {
+		if c.(*condition).re != nil {
+			t.Errorf("cache of %s allocated before RegexpValue", c.Key())
+		}
+	}
+	re, _ := f.First().RegexpValue()
+	if f.First().(*condition).re == nil {
+		t.Fatalf("cache not allocated by RegexpValue")
+	}
+	for name, g := range map[string]Filter{
+		"Clone":     f.Clone(),
+		"Without":   f.Without("bar"),
+		"Rewrite":   f.Rewrite(func(c Condition) (Condition, bool) { return c, true }),
+		"Subfilter": f.Subfilter(""),
+	} {
+		if g.First() == nil {
+			continue
+		}
+		if g.First().(*condition).re != nil {
+			t.Errorf("%s shares the cache", name)
+		}
+		if again, err := g.First().RegexpValue(); err != nil || again == re || again.String() != re.String() {
+			t.Errorf("%s RegexpValue() got = %p, %v, want a new ^a$", name, again, err)
+		}
+	}
+}
+
+func Test_condition_Clone(t *testing.T) {
+	f, _ := NewParser().Parse("foo.bar=42 AND bla=vla")
+	orig := f.First()
+	got := orig.Clone()
+	if got.Key() != orig.Key() || got.Op() != orig.Op() || got.StringValue() != orig.StringValue() {
+		t.Errorf("Clone() = %v, want %v", got, orig)
+	}
+	if !reflect.DeepEqual(got.KeyParts(), orig.KeyParts()) {
+		t.Errorf("KeyParts() = %v, want %v", got.KeyParts(), orig.KeyParts())
+	}
+	if and, or := got.AndOr(); and != nil || or != nil {
+		t.Errorf("expected no links, got %v, %v", and, or)
+	}
+	if i, err := got.IntValue(); err != nil || i != 42 {
+		t.Errorf("IntValue() = %v, %v", i, err)
+	}
+	got.KeyParts()[0] = "moo"
+	if orig.KeyParts()[0] != "foo" {
+		t.Errorf("original key parts changed to %v", orig.KeyParts())
+	}
+}
+
+func Test_condition_WithOp(t *testing.T) {
+	tests := []struct {
+		op      string
+		want    Condition
+		wantErr bool
+	}{
+		{"eq", NewCondition("foo.bar", []string{"foo", "bar"}, "eq", "bla"), false},
+		{"!=", NewCondition("foo.bar", []string{"foo", "bar"}, "!=", "bla"), false},
+		{"", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.op, func(t *testing.T) {
+			f, _ := NewParser().Parse("foo.bar=bla AND moo=boo")
+			got, err := f.First().WithOp(tt.op)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WithOp() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && !conditionsEqual(got, tt.want) {
+				t.Errorf("WithOp() = %v, want %v", got, tt.want)
+			}
+			if f.First().Op() != "=" {
+				t.Errorf("original operator changed to %v", f.First().Op())
+			}
+		})
+	}
+}
+
+func Test_condition_Negate(t *testing.T) {
+	tests := []struct {
+		op      string
+		want    string
+		wantErr bool
+	}{
+		{"=", "!=", false},
+		{"!=", "=", false},
+		{"<", ">=", false},
+		{">=", "<", false},
+		{">", "<=", false},
+		{"<=", ">", false},
+		{"~", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.op, func(t *testing.T) {
+			f, _ := NewParser(OptionOperators(tt.op)).Parse("foo" + tt.op + "bar AND bla=vla")
+			got, err := f.First().Negate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Negate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				if err.Error() != "cannot negate operator "+tt.op {
+					t.Errorf("unexpected error message %q", err)
+				}
+				return
+			}
+			want := NewCondition("foo", []string{"foo"}, tt.want, "bar")
+			if !conditionsEqual(got, want) {
+				t.Errorf("Negate() = %v, want %v", got, want)
+			}
+			if f.First().Op() != tt.op {
+				t.Errorf("original operator changed to %v", f.First().Op())
+			}
+		})
+	}
+}
+
+func Test_condition_WithValue(t *testing.T) {
+	f, _ := NewParser().Parse("foo.bar!=BLA AND moo=boo")
+	c := f.First()
+	got := c.WithValue(strings.ToLower(c.StringValue()))
+	want := NewCondition("foo.bar", []string{"foo", "bar"}, "!=", "bla")
+	if !conditionsEqual(got, want) {
+		t.Errorf("WithValue() = %v, want %v", got, want)
+	}
+	if c.StringValue() != "BLA" || c.And() == nil {
+		t.Errorf("original changed to %v", c)
+	}
+	if got := c.WithValue("42"); got.StringValue() != "42" {
+		t.Errorf("WithValue() = %v", got)
+	} else if i, err := got.IntValue(); err != nil || i != 42 {
+		t.Errorf("IntValue() = %v, %v", i, err)
+	}
+}
+
 func Test_snakeCase(t *testing.T) {
 	type args struct {
 		s string
@@ -823,7 +1626,7 @@ func Test_filter_String(t *testing.T) {
 		{"double", "foo=bar AND bla=vla", "foo=bar AND bla=vla"},
 		{"triple", "foo=bar AND bla=vla OR moo=boo", "foo=bar AND bla=vla OR moo=boo"},
 		{"empty", "", ""},
-		{"trim spaces", "foo=\" bar\"  AND bla=vla", "foo= bar AND bla=vla"},
+		{"trim spaces", "foo=\" bar\"  AND bla=vla", "foo=\" bar\" AND bla=vla"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,94 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+// Package firestorequery applies filters to Firestore queries. It is a
+// separate module, so that the listfilter package itself does not depend on
+// the Firestore client.
+package firestorequery
+
+import (
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"github.com/HayoVanLoon/go-listfilter"
+)
+
+// MaxDisjunctions is the maximum number of disjunctions Firestore allows in
+// the disjunctive normal form of a query's filters.
+const MaxDisjunctions = 30
+
+// operators maps filter operators to Firestore operators.
+var operators = map[string]string{
+	"=":  "==",
+	"!=": "!=",
+	"<":  "<",
+	">":  ">",
+	"<=": "<=",
+	">=": ">=",
+	":":  "array-contains",
+}
+
+// Apply returns the query with the filter added to it. See EntityFilter.
+func Apply(q firestore.Query, f listfilter.Filter) (firestore.Query, error) {
+	ef, err := EntityFilter(f)
+	if err != nil || ef == nil {
+		return q, err
+	}
+	return q.WhereEntity(ef), nil
+}
+
+// EntityFilter translates the filter into a Firestore filter, or nil if the
+// filter is empty. The operators '=', '!=', '<', '>', '<=' and '>=' are
+// mapped to their Firestore equivalent and the has operator ':' to
+// 'array-contains'. Keys are used as dot-separated field paths and values
+// are converted with listfilter.TypedValue.
+//
+// Filters that Firestore cannot run result in an error: those with other
+// operators, with more than MaxDisjunctions disjunctions (the product of the
+// sizes of the OR groups) and those in which a disjunction could contain more
+// than one 'array-contains', which is the case when more than one OR group
+// uses the has operator.
+func EntityFilter(f listfilter.Filter) (firestore.EntityFilter, error) {
+	var ands, ors []firestore.EntityFilter
+	disjunctions, containsGroups := 1, 0
+	contains := false
+	for _, c := range f.Conditions() {
+		op, ok := operators[c.Op()]
+		if !ok {
+			return nil, fmt.Errorf("%s: unsupported operator %s", c.Key(), c.Op())
+		}
+		contains = contains || c.Op() == ":"
+		ors = append(ors, firestore.PropertyFilter{
+			Path:     c.Key(),
+			Operator: op,
+			Value:    listfilter.TypedValue(c),
+		})
+		if _, or := c.AndOr(); or != nil {
+			continue
+		}
+		disjunctions *= len(ors)
+		if disjunctions > MaxDisjunctions {
+			return nil, fmt.Errorf("filter has more than %d disjunctions", MaxDisjunctions)
+		}
+		if contains {
+			containsGroups += 1
+			if containsGroups > 1 {
+				return nil, fmt.Errorf("filter has a disjunction with more than one has (:) condition")
+			}
+		}
+		if len(ors) == 1 {
+			ands = append(ands, ors[0])
+		} else {
+			ands = append(ands, firestore.OrFilter{Filters: ors})
+		}
+		ors, contains = nil, false
+	}
+	switch len(ands) {
+	case 0:
+		return nil, nil
+	case 1:
+		return ands[0], nil
+	}
+	return firestore.AndFilter{Filters: ands}, nil
+}
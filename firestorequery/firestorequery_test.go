@@ -0,0 +1,110 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package firestorequery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	pb "cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/HayoVanLoon/go-listfilter"
+	"google.golang.org/api/option"
+	"google.golang.org/protobuf/proto"
+)
+
+func serialize(t *testing.T, q firestore.Query) *pb.RunQueryRequest {
+	t.Helper()
+	bs, err := q.Serialize()
+	if err != nil {
+		t.Fatalf("unexpected error serialising query: %v", err)
+	}
+	req := &pb.RunQueryRequest{}
+	if err := proto.Unmarshal(bs, req); err != nil {
+		t.Fatalf("unexpected error unmarshalling query: %v", err)
+	}
+	return req
+}
+
+func TestApply(t *testing.T) {
+	client, err := firestore.NewClient(context.Background(), "project", option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	defer client.Close()
+	q := client.Collection("things").Query
+
+	prop := func(path, op string, v any) firestore.PropertyFilter {
+		return firestore.PropertyFilter{Path: path, Operator: op, Value: v}
+	}
+	p := listfilter.NewParser(listfilter.OptionOperators("<", ">", "<=", ">=", ":", "~"))
+	tests := []struct {
+		name    string
+		query   string
+		want    firestore.Query
+		wantErr bool
+	}{
+		{"empty", "", q, false},
+		{"single", "foo=bar", q.WhereEntity(prop("foo", "==", "bar")), false},
+		{
+			"typed values",
+			`a=1 AND b=1.5 AND c=true AND d="1" AND e>=2024-03-01T12:00:00Z`,
+			q.WhereEntity(firestore.AndFilter{Filters: []firestore.EntityFilter{
+				prop("a", "==", int64(1)),
+				prop("b", "==", 1.5),
+				prop("c", "==", true),
+				prop("d", "==", "1"),
+				prop("e", ">=", time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)),
+			}}),
+			false,
+		},
+		{"not equal", "foo!=bar", q.WhereEntity(prop("foo", "!=", "bar")), false},
+		{"has", "tags:a", q.WhereEntity(prop("tags", "array-contains", "a")), false},
+		{"nested key", "foo.bar<3", q.WhereEntity(prop("foo.bar", "<", int64(3))), false},
+		{
+			"or groups",
+			"a=1 OR a=2 AND b>3 AND c:x OR c:y",
+			q.WhereEntity(firestore.AndFilter{Filters: []firestore.EntityFilter{
+				firestore.OrFilter{Filters: []firestore.EntityFilter{
+					prop("a", "==", int64(1)),
+					prop("a", "==", int64(2)),
+				}},
+				prop("b", ">", int64(3)),
+				firestore.OrFilter{Filters: []firestore.EntityFilter{
+					prop("c", "array-contains", "x"),
+					prop("c", "array-contains", "y"),
+				}},
+			}}),
+			false,
+		},
+		{"unsupported operator", "foo~bar", q, true},
+		{"multiple has", "a:x AND b:y", q, true},
+		{
+			"too many disjunctions",
+			"a=1 OR a=2 OR a=3 OR a=4 AND b=1 OR b=2 OR b=3 OR b=4 AND c=1 OR c=2",
+			q,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error parsing %q: %v", tt.query, err)
+			}
+			actual, err := Apply(q, f)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if want, got := serialize(t, tt.want), serialize(t, actual); !proto.Equal(want, got) {
+				t.Errorf("expected %v,\ngot %v", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+func (f filter) Canonical() string {
+	gs := orGroups(f)
+	sort.SliceStable(gs, func(i, j int) bool {
+		return lessCondition(gs[i][0], gs[j][0])
+	})
+	b := strings.Builder{}
+	for i, g := range gs {
+		if i > 0 {
+			b.WriteString(" " + separatorAnd + " ")
+		}
+		for j, c := range g {
+			if j > 0 {
+				b.WriteString(" " + separatorOr + " ")
+			}
+			b.WriteString(c.Key() + c.Op() + f.formatValue(c.Op(), c.StringValue()))
+		}
+	}
+	return b.String()
+}
+
+// aip160Operators are the comparison operators of AIP-160.
+var aip160Operators = map[string]bool{
+	"=": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true, ":": true,
+}
+
+// aip160Word matches values that need no quotes in AIP-160.
+var aip160Word = regexp.MustCompile(`^[A-Za-z_*][A-Za-z0-9_*]*$`)
+
+func (f filter) ToAIP160() (string, error) {
+	b := strings.Builder{}
+	cs := f.Conditions()
+	for i, c := range cs {
+		if !aip160Operators[c.Op()] {
+			return "", fmt.Errorf("%s: operator %s is not supported by AIP-160", c.Key(), c.Op())
+		}
+		if i > 0 {
+			if and, _ := cs[i-1].AndOr(); and != nil {
+				b.WriteString(" " + separatorAnd + " ")
+			} else {
+				b.WriteString(" " + separatorOr + " ")
+			}
+		}
+		v := c.StringValue()
+		if c.Op() == "!=" && v == "*" && !c.IsQuoted() {
+			b.WriteString("-" + c.Key() + ":*")
+			continue
+		}
+		b.WriteString(c.Key() + c.Op() + aip160Value(v, c.IsQuoted()))
+	}
+	return b.String(), nil
+}
+
+// aip160Value returns the value as it should appear in an AIP-160 filter.
+func aip160Value(v string, isQuoted bool) string {
+	if _, ok := number(v); ok && !isQuoted {
+		return v
+	}
+	switch v {
+	case separatorAnd, separatorOr, "NOT":
+		return quoted(v)
+	}
+	if isQuoted || !aip160Word.MatchString(v) {
+		return quoted(v)
+	}
+	return v
+}
+
+// lessCondition orders conditions by key, operator and value.
+func lessCondition(a, b Condition) bool {
+	if a.Key() != b.Key() {
+		return a.Key() < b.Key()
+	}
+	if a.Op() != b.Op() {
+		return a.Op() < b.Op()
+	}
+	return a.StringValue() < b.StringValue()
+}
+
+// formatCondition returns the condition as it should appear in a filter
+// string. Values that were quoted remain quoted.
+func (f filter) formatCondition(c *condition) string {
+	v := c.stringValue
+	if c.quoted {
+		v = quoted(v)
+	} else {
+		v = f.formatValue(c.op, v)
+	}
+	return c.key + c.op + v
+}
+
+// formatValue returns the value as it should appear in a filter string after
+// the operator, quoting it only if necessary.
+func (f filter) formatValue(op, v string) string {
+	if needsQuotes(v) || f.extendsOperator(op, v) {
+		return quoted(v)
+	}
+	return v
+}
+
+// needsQuotes reports whether the value can only be represented as a quoted
+// value.
+func needsQuotes(v string) bool {
+	if strings.HasPrefix(v, string(quote)) {
+		return true
+	}
+	return strings.IndexFunc(v, unicode.IsSpace) >= 0
+}
+
+// extendsOperator reports whether an unquoted value would be read as part of
+// a longer (registered) operator.
+func (f filter) extendsOperator(op, v string) bool {
+	ops := f.ops
+	if ops == nil {
+		ops = defaultOperators
+	}
+	for o := range ops {
+		if len(o) > len(op) && strings.HasPrefix(op+v, o) {
+			return true
+		}
+	}
+	return false
+}
+
+// quoted returns the value as a quoted value, escaping quotes and escape
+// characters.
+func quoted(v string) string {
+	b := strings.Builder{}
+	b.WriteRune(quote)
+	for _, r := range v {
+		if r == quote || r == escapeCharacter {
+			b.WriteRune(escapeCharacter)
+		}
+		b.WriteRune(r)
+	}
+	b.WriteRune(quote)
+	return b.String()
+}
@@ -0,0 +1,185 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"testing"
+)
+
+func TestFilter_Canonical(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"empty", "", ""},
+		{"single", "a=1", "a=1"},
+		{"sorted by key", "b=2 AND a=1", "a=1 AND b=2"},
+		{"already sorted", "a=1 AND b=2", "a=1 AND b=2"},
+		{"sorted by operator", "a=1 AND a!=2", "a!=2 AND a=1"},
+		{"sorted by value", "a=2 AND a=1", "a=1 AND a=2"},
+		{"whitespace", "b=2\t AND\n a=1", "a=1 AND b=2"},
+		{"unnecessary quotes", `b="2" AND a=1`, "a=1 AND b=2"},
+		{"necessary quotes", `b="2 3" AND a="\"x\""`, `a="\"x\"" AND b="2 3"`},
+		{"escape characters", `a="x \\ y"`, `a="x \\ y"`},
+		{"or group order kept", "c=3 AND b=2 OR a=1", "b=2 OR a=1 AND c=3"},
+		{"or groups ordered by first", "d=4 OR a=1 AND b=2 OR c=3", "b=2 OR c=3 AND d=4 OR a=1"},
+		{"nested keys", "a.c=1 AND a.b=2 AND a=3", "a=3 AND a.b=2 AND a.c=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewParser().Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			if got := f.Canonical(); got != tt.want {
+				t.Errorf("Canonical() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter_Canonical_equal(t *testing.T) {
+	f1, _ := NewParser().Parse("b=2 AND a=1")
+	f2, _ := NewParser().Parse("a=1 AND b=2")
+	if f1.Canonical() != f2.Canonical() {
+		t.Errorf("expected %v to equal %v", f1.Canonical(), f2.Canonical())
+	}
+	reparsed, err := NewParser().Parse(f1.Canonical())
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	if !reparsed.EqualUnordered(f1) {
+		t.Errorf("expected %v to equal %v", reparsed, f1)
+	}
+}
+
+func TestFilter_String_roundTrip(t *testing.T) {
+	corpus := []string{
+		"",
+		"foo=bar",
+		"foo=bar AND bla=vla OR moo=boo",
+		"fo_o1=\ud185",
+		"fo_o1=\"\ud185\"",
+		"foo.bar.bla=vla",
+		"foo==",
+		"foo=bar AND\n\tbla=vla   AND moo=boo",
+		"foo=",
+		"foo=\"\"",
+		"foo=\"say \\\"bar\\\"\"",
+		"foo=\"say\\\\ \\n \\\"bar\\\"\"",
+		"foo=\"hello world\" AND bar=1",
+		"foo=\" bar\"  AND bla=vla",
+		"foo=\"a\tb\" OR bar=\"x AND y\"",
+		"foo=\"\\\"\" AND bar=1",
+		"foo=a\"b",
+		"foo=a\\b",
+		"foo<\"=5\" AND bar<=5",
+	}
+	opts := []Option{OptionOperators("<", "<=")}
+	for _, s := range corpus {
+		t.Run(s, func(t *testing.T) {
+			f, err := NewParser(opts...).Parse(s)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got, err := NewParser(opts...).Parse(f.String())
+			if err != nil {
+				t.Fatalf("could not parse %q: %v", f.String(), err)
+			}
+			if !got.Equal(f) {
+				t.Errorf("Parse(String()) = %v, want %v", got, f)
+			}
+			gs, fs := got.Conditions(), f.Conditions()
+			for i := range gs {
+				if gs[i].IsQuoted() != fs[i].IsQuoted() {
+					t.Errorf("IsQuoted() = %v, want %v", gs[i].IsQuoted(), fs[i].IsQuoted())
+				}
+			}
+			if got.String() != f.String() {
+				t.Errorf("String() = %v, want %v", got.String(), f.String())
+			}
+		})
+	}
+}
+
+func TestFilter_String_quoting(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"spaces", `foo="hello world" AND bar=1`, `foo="hello world" AND bar=1`},
+		{"originally quoted", `foo="bar"`, `foo="bar"`},
+		{"escaped", `foo="a \"b\" \\ c"`, `foo="a \"b\" \\ c"`},
+		{"unknown escape kept", `foo="a\nb"`, `foo="a\\nb"`},
+		{"operator prefix", `foo<"=5"`, `foo<"=5"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewParser(OptionOperators("<", "<=")).Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			if got := f.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter_String_built(t *testing.T) {
+	f, err := NewFilterBuilder(OptionOperators("<", "<=")).And("foo", "<", "=5").And("bar", "=", "a b").Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := f.String(), `foo<"=5" AND bar="a b"`; got != want {
+		t.Errorf("String() = %v, want %v", got, want)
+	}
+}
+
+func TestFilter_ToAIP160(t *testing.T) {
+	p := NewParser(OptionOperators("<", ">", "<=", ">=", ":", "~"))
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{"empty", "", "", false},
+		{"single", "a=1", "a=1", false},
+		{"whitespace", "a=1\t AND\n b=2 OR  c=3", "a=1 AND b=2 OR c=3", false},
+		{"has", "tags:urgent", "tags:urgent", false},
+		{"presence", "owner:*", "owner:*", false},
+		{"negated presence", "owner!=*", "-owner:*", false},
+		{"quoted star", `owner!="*"`, `owner!="*"`, false},
+		{"not equal", "state!=DONE", "state!=DONE", false},
+		{"numbers", "a>=-1.5 AND b<1e3", "a>=-1.5 AND b<1e3", false},
+		{"wildcard", "name=foo*", "name=foo*", false},
+		{"spaces", `title="hello world"`, `title="hello world"`, false},
+		{"originally quoted", `a="1" AND b="foo"`, `a="1" AND b="foo"`, false},
+		{"timestamp", "create_time>2024-01-01T00:00:00Z", `create_time>"2024-01-01T00:00:00Z"`, false},
+		{"punctuation", "a=foo.bar AND b=x-y", `a="foo.bar" AND b="x-y"`, false},
+		{"keyword", `a=AND OR a=NOT`, `a="AND" OR a="NOT"`, false},
+		{"escaping", `a="say \"hi\" \\ bye"`, `a="say \"hi\" \\ bye"`, false},
+		{"nested key", "owner.name=joe", "owner.name=joe", false},
+		{"! unsupported operator", "a~b", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got, err := f.ToAIP160()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToAIP160() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ToAIP160() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	globAny = '*'
+	globOne = '?'
+)
+
+// A globToken is a literal rune or one of the wildcards.
+type globToken struct {
+	r        rune
+	wildcard bool
+}
+
+// parseGlob splits s into glob tokens. An asterisk matches any sequence of
+// runes and a question mark a single rune. A backslash makes the next rune
+// literal. The second return value reports whether s has any wildcards,
+// escaped or not, and so must be matched as a pattern.
+func parseGlob(s string) ([]globToken, bool) {
+	var ts []globToken
+	isPattern := false
+	escape := false
+	for _, r := range s {
+		switch {
+		case escape:
+			if r == globAny || r == globOne {
+				isPattern = true
+			} else if r != escapeCharacter {
+				// no special meaning, keep escape character
+				ts = append(ts, globToken{escapeCharacter, false})
+			}
+			ts = append(ts, globToken{r, false})
+			escape = false
+		case r == escapeCharacter:
+			escape = true
+		case r == globAny || r == globOne:
+			ts = append(ts, globToken{r, true})
+			isPattern = true
+		default:
+			ts = append(ts, globToken{r, false})
+		}
+	}
+	if escape {
+		ts = append(ts, globToken{escapeCharacter, false})
+	}
+	return ts, isPattern
+}
+
+// matchGlob reports whether s matches the glob pattern ts as a whole. If fold
+// is set, literal runes are compared case-insensitively.
+func matchGlob(ts []globToken, s string, fold bool) bool {
+	// position in the pattern and in s to backtrack to after the last '*'
+	star, backtrack := -1, 0
+	i, j := 0, 0
+	for j < len(s) {
+		r, width := utf8.DecodeRuneInString(s[j:])
+		if i < len(ts) {
+			t := ts[i]
+			switch {
+			case t.wildcard && t.r == globAny:
+				star, backtrack = i, j
+				i += 1
+				continue
+			case t.wildcard || runesEqual(t.r, r, fold):
+				i += 1
+				j += width
+				continue
+			}
+		}
+		if star < 0 {
+			return false
+		}
+		// let the last '*' consume one more rune
+		_, width = utf8.DecodeRuneInString(s[backtrack:])
+		backtrack += width
+		i, j = star+1, backtrack
+	}
+	for i < len(ts) && ts[i].wildcard && ts[i].r == globAny {
+		i += 1
+	}
+	return i == len(ts)
+}
+
+func runesEqual(a, b rune, fold bool) bool {
+	if a == b {
+		return true
+	}
+	if !fold {
+		return false
+	}
+	for r := unicode.SimpleFold(a); r != a; r = unicode.SimpleFold(r) {
+		if r == b {
+			return true
+		}
+	}
+	return false
+}
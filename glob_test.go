@@ -0,0 +1,85 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		s       string
+		fold    bool
+		want    bool
+	}{
+		{"trailing", "ba*", "bar", false, true},
+		{"trailing, empty rest", "ba*", "ba", false, true},
+		{"trailing, no match", "ba*", "foo", false, false},
+		{"leading", "*.txt", "notes.txt", false, true},
+		{"leading, no match", "*.txt", "notes.txt.bak", false, false},
+		{"inner", "a*c", "abbbc", false, true},
+		{"inner, backtracking", "a*bc", "abcbc", false, true},
+		{"several", "*a*b*", "xxaxxbxx", false, true},
+		{"only star", "*", "", false, true},
+		{"single", "b?r", "bar", false, true},
+		{"single, too short", "b?r", "br", false, false},
+		{"single, multi-byte", "caf?", "café", false, true},
+		{"single, multi-byte, one rune", "?", "日", false, true},
+		{"escaped star", `a\*`, "a*", false, true},
+		{"escaped star, not a wildcard", `a\*`, "ab", false, false},
+		{"escaped question mark", `a\?`, "a?", false, true},
+		{"escaped backslash", `a\\*`, `a\b`, false, true},
+		{"other escape kept", `a\b*`, `a\bc`, false, true},
+		{"case sensitive", "BA*", "bar", false, false},
+		{"fold", "BA*", "bar", true, true},
+		{"fold, multi-byte", "ÉT?", "été", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, _ := parseGlob(tt.pattern)
+			if got := matchGlob(ts, tt.s, tt.fold); got != tt.want {
+				t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatcher_glob(t *testing.T) {
+	type Item struct {
+		Name string
+		File string
+	}
+	p := NewParser(OptionOperators(":"))
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"name=ba*", true},
+		{"name=BA*", false},
+		{"name!=ba*", false},
+		{"name!=fo*", true},
+		{"name:b?z", true},
+		{"file=*.txt", true},
+		{"file=*.csv", false},
+		{`name="b*"`, true},
+		{`name=b\*`, false},
+		{"name=baz", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			doc := map[string]any{"name": "baz", "file": "notes.txt"}
+			if got, err := f.MatchDocument(doc); err != nil || got != tt.want {
+				t.Errorf("MatchDocument() got = %v, %v, want %v", got, err, tt.want)
+			}
+			if got, err := f.MatchStruct(Item{"baz", "notes.txt"}); err != nil || got != tt.want {
+				t.Errorf("MatchStruct() got = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
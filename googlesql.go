@@ -0,0 +1,34 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"strconv"
+	"strings"
+)
+
+// googleSQLEscaper escapes the contents of a quoted GoogleSQL identifier.
+var googleSQLEscaper = strings.NewReplacer(`\`, `\\`, "`", "\\`")
+
+// ToGoogleSQL translates the filter into the condition of a WHERE clause in
+// GoogleSQL, as used by BigQuery and Spanner, like ToSQL does. Identifiers
+// are quoted with backticks and the parts of dotted keys become field
+// accesses, like `a`.`b`. Values are passed as named parameters, @p0, @p1,
+// ..., which are returned in params (without '@'). The values are converted
+// with TypedValue. The SQLOptionDialect option is ignored.
+func ToGoogleSQL(f Filter, opts ...SQLOption) (clause string, params map[string]any, err error) {
+	cfg := newSQLConfig(opts)
+	cfg.dialect = sqlDialectGoogle
+	params = make(map[string]any)
+	clause, err = cfg.render(f, func(c Condition) string {
+		name := "p" + strconv.Itoa(len(params))
+		params[name] = TypedValue(c)
+		return "@" + name
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return clause, params, nil
+}
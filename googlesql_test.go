@@ -0,0 +1,95 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestToGoogleSQL(t *testing.T) {
+	p := NewParser(OptionOperators("<", ">=", ":"))
+	tests := []struct {
+		name       string
+		query      string
+		opts       []SQLOption
+		want       string
+		wantParams map[string]any
+		wantErr    bool
+	}{
+		{"empty", "", nil, "", map[string]any{}, false},
+		{
+			"mixed",
+			"createdAt>=2024-01-01T00:00:00Z AND status=open OR status=pending",
+			[]SQLOption{SQLOptionColumns(map[string]string{"createdAt": "created_at", "status": "status"})},
+			"`created_at` >= @p0 AND (`status` = @p1 OR `status` = @p2)",
+			map[string]any{
+				"p0": time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				"p1": "open",
+				"p2": "pending",
+			},
+			false,
+		},
+		{
+			"typed values",
+			`a=true AND b=42 AND c<1.5 AND d="42" AND e=2024-01-01 AND f!=inf`,
+			nil,
+			"`a` = @p0 AND `b` = @p1 AND `c` < @p2 AND `d` = @p3 AND `e` = @p4 AND `f` <> @p5",
+			map[string]any{"p0": true, "p1": int64(42), "p2": 1.5, "p3": "42", "p4": "2024-01-01", "p5": "inf"},
+			false,
+		},
+		{
+			"nested field",
+			"owner.address.city=Utrecht",
+			nil,
+			"`owner`.`address`.`city` = @p0",
+			map[string]any{"p0": "Utrecht"},
+			false,
+		},
+		{
+			"dialect ignored",
+			"a=1",
+			[]SQLOption{SQLOptionDialect(SQLDialectPostgres)},
+			"`a` = @p0",
+			map[string]any{"p0": int64(1)},
+			false,
+		},
+		{"! unsupported operator", "tags:x", nil, "", nil, true},
+		{"! strict", "secret=x", []SQLOption{SQLOptionStrict()}, "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got, params, err := ToGoogleSQL(f, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToGoogleSQL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ToGoogleSQL() got = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(params, tt.wantParams) {
+				t.Errorf("ToGoogleSQL() params = %#v, want %#v", params, tt.wantParams)
+			}
+		})
+	}
+}
+
+func TestToGoogleSQL_quoting(t *testing.T) {
+	f, err := NewParser().Parse("odd=x")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	got, _, err := ToGoogleSQL(f, SQLOptionColumns(map[string]string{"odd": "we`i\\rd"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "`we\\`i\\\\rd` = @p0"; got != want {
+		t.Errorf("ToGoogleSQL() got = %v, want %v", got, want)
+	}
+}
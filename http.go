@@ -0,0 +1,105 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"context"
+	"net/http"
+)
+
+type filterContextKey struct{}
+
+// FilterMiddleware returns an HTTP middleware that parses the filter from the
+// query parameter with the given name and stores it in the request context,
+// from where it can be retrieved with FilterFromContext. An absent parameter
+// results in an empty Filter.
+// If parsing fails, the middleware responds with 400 Bad Request and the
+// ParseError message, and the wrapped handler is not called.
+func FilterMiddleware(paramName string, opts ...Option) func(http.Handler) http.Handler {
+	p := NewParser(opts...)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			f, err := p.Parse(r.URL.Query().Get(paramName))
+			if err != nil {
+				msg := err.Error()
+				if pe, ok := err.(ParseError); ok {
+					msg = pe.Message()
+				}
+				http.Error(w, msg, http.StatusBadRequest)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), f)))
+		})
+	}
+}
+
+// ParseRequest parses the filter from the request parameter with the given
+// name, or "filter" if name is empty. An absent parameter results in an empty
+// Filter. If the parameter occurs more than once, the filters are combined
+// with AND. Parameters in form bodies of POST, PUT and PATCH requests (see
+// http.Request.ParseForm) are included, before those in the URL. Errors from
+// the parser are returned as is, so that a ParseError can be recognised.
+func ParseRequest(p Parser, r *http.Request, name string) (Filter, error) {
+	if name == "" {
+		name = "filter"
+	}
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	var fs []Filter
+	for _, s := range r.Form[name] {
+		f, err := p.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, f)
+	}
+	switch len(fs) {
+	case 0:
+		return p.Parse("")
+	case 1:
+		return fs[0], nil
+	}
+	return andFilters(fs), nil
+}
+
+// andFilters combines the filters with AND. The result has the operators of
+// the first filter.
+func andFilters(fs []Filter) Filter {
+	var cs []condition
+	var seps []string
+	for _, f := range fs {
+		fcs := f.Conditions()
+		for i, c := range fcs {
+			if len(cs) > 0 {
+				sep := separatorAnd
+				if i > 0 {
+					if _, or := fcs[i-1].AndOr(); or != nil {
+						sep = separatorOr
+					}
+				}
+				seps = append(seps, sep)
+			}
+			cs = append(cs, toCondition(c))
+		}
+	}
+	nf := newFilter(cs, seps)
+	if f, ok := fs[0].(filter); ok {
+		nf.ops = f.ops
+	}
+	return nf
+}
+
+// NewContext returns a copy of ctx carrying the Filter.
+func NewContext(ctx context.Context, f Filter) context.Context {
+	return context.WithValue(ctx, filterContextKey{}, f)
+}
+
+// FilterFromContext retrieves the Filter stored in the context by
+// FilterMiddleware. The boolean is false if there is none.
+func FilterFromContext(ctx context.Context) (Filter, bool) {
+	f, ok := ctx.Value(filterContextKey{}).(Filter)
+	return f, ok
+}
@@ -0,0 +1,132 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestFilterMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		opts       []Option
+		wantStatus int
+		wantFilter string
+		wantBody   string
+	}{
+		{"simple", "foo=bar", nil, http.StatusOK, "foo=bar", ""},
+		{"absent", "", nil, http.StatusOK, "", ""},
+		{"with option", "fooBar=bla", []Option{OptionSnakeCase()}, http.StatusOK, "foo_bar=bla", ""},
+		{"! parse error", "foo", nil, http.StatusBadRequest, "", "expected operator\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Filter
+			called := false
+			h := FilterMiddleware("filter", tt.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				got, _ = FilterFromContext(r.Context())
+			}))
+			target := "/things"
+			if tt.query != "" {
+				target += "?filter=" + url.QueryEscape(tt.query)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if called {
+					t.Errorf("handler should not have been called")
+				}
+				if body := rec.Body.String(); !strings.HasPrefix(body, tt.wantBody) {
+					t.Errorf("expected body %q, got %q", tt.wantBody, body)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("expected filter in context")
+			}
+			if got.String() != tt.wantFilter {
+				t.Errorf("expected filter %q, got %q", tt.wantFilter, got.String())
+			}
+		})
+	}
+}
+
+func TestParseRequest(t *testing.T) {
+	form := "application/x-www-form-urlencoded"
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		param       string
+		want        string
+		wantErr     bool
+	}{
+		{"absent", http.MethodGet, "/things", "", "", "", "", false},
+		{"default name", http.MethodGet, "/things?filter=foo%3Dbar", "", "", "", "foo=bar", false},
+		{"custom name", http.MethodGet, "/things?q=foo%3Dbar&filter=x%3Dy", "", "", "q", "foo=bar", false},
+		{"plus is space", http.MethodGet, "/things?filter=foo%3Dbar+AND+bla%3Dvla", "", "", "", "foo=bar AND bla=vla", false},
+		{"encoded quotes", http.MethodGet, "/things?filter=foo%3D%22a+b%2Bc%22", "", "", "", `foo="a b+c"`, false},
+		{"encoded space", http.MethodGet, "/things?filter=foo%3D%22a%20b%22", "", "", "", `foo="a b"`, false},
+		{
+			"repeated",
+			http.MethodGet,
+			"/things?filter=a%3D1+OR+a%3D2&filter=b%3D3",
+			"", "", "",
+			"a=1 OR a=2 AND b=3",
+			false,
+		},
+		{"form body", http.MethodPost, "/things", form, "filter=foo%3Dbar", "", "foo=bar", false},
+		{"form body and query", http.MethodPost, "/things?filter=a%3D1", form, "filter=b%3D2", "", "b=2 AND a=1", false},
+		{"other content type", http.MethodPost, "/things", "application/json", "filter=foo%3Dbar", "", "", false},
+		{"body ignored for GET", http.MethodGet, "/things", form, "filter=foo%3Dbar", "", "", false},
+		{"! unencoded plus", http.MethodGet, "/things?filter=foo%3Da+b", "", "", "", "", true},
+		{"! second invalid", http.MethodGet, "/things?filter=a%3D1&filter=b", "", "", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			got, err := ParseRequest(NewParser(), r, tt.param)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if _, ok := err.(ParseError); !ok {
+					t.Errorf("expected ParseError, got %T", err)
+				}
+				return
+			}
+			if got.String() != tt.want {
+				t.Errorf("ParseRequest() = %q, want %q", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterFromContext(t *testing.T) {
+	if _, ok := FilterFromContext(context.Background()); ok {
+		t.Errorf("expected no filter in empty context")
+	}
+	f, _ := NewParser().Parse("foo=bar")
+	got, ok := FilterFromContext(NewContext(context.Background(), f))
+	if !ok || got.String() != "foo=bar" {
+		t.Errorf("expected %v, got %v", f, got)
+	}
+}
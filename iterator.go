@@ -0,0 +1,1029 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Done is returned by an Iterator's Next method when there are no more
+// elements.
+var Done = errors.New("no more items in iterator")
+
+// Timeout is returned by an Iterator's Next method when an element did not
+// become available in time.
+var Timeout = errors.New("iterator timed out")
+
+// Canceled is returned by an Iterator's Next method when the iterator was
+// canceled before it was exhausted.
+var Canceled = errors.New("iterator canceled")
+
+// An Iterator provides elements one at a time. Next returns the next element,
+// or Done when the iterator is exhausted. Any other error is an actual error.
+// Once Next has returned Done, subsequent calls should do the same.
+type Iterator[T any] interface {
+	Next() (T, error)
+}
+
+// iteratorFunc is an Iterator that calls itself.
+type iteratorFunc[T any] func() (T, error)
+
+func (f iteratorFunc[T]) Next() (T, error) {
+	return f()
+}
+
+// ForSlice returns an Iterator over the elements of xs.
+func ForSlice[T any](xs []T) Iterator[T] {
+	i := 0
+	return iteratorFunc[T](func() (T, error) {
+		if i >= len(xs) {
+			var zero T
+			return zero, Done
+		}
+		i += 1
+		return xs[i-1], nil
+	})
+}
+
+// ForLines returns an Iterator over the lines read from r, without their line
+// endings ("\n" or "\r\n"). Read errors are returned as is. Lines longer than
+// bufio.MaxScanTokenSize result in an error; see ForLinesWithBuffer.
+func ForLines(r io.Reader) Iterator[string] {
+	return forScanner(bufio.NewScanner(r))
+}
+
+// ForLinesWithBuffer is like ForLines, but allows lines of up to maxBytes
+// bytes. Panics if maxBytes is not positive.
+func ForLinesWithBuffer(r io.Reader, maxBytes int) Iterator[string] {
+	if maxBytes <= 0 {
+		panic("listfilter: line buffer size must be positive")
+	}
+	sc := bufio.NewScanner(r)
+	size := 4096
+	if maxBytes < size {
+		size = maxBytes
+	}
+	sc.Buffer(make([]byte, 0, size), maxBytes)
+	return forScanner(sc)
+}
+
+// forScanner returns an Iterator over the tokens read by sc.
+func forScanner(sc *bufio.Scanner) Iterator[string] {
+	return iteratorFunc[string](func() (string, error) {
+		if sc.Scan() {
+			return sc.Text(), nil
+		}
+		if err := sc.Err(); err != nil {
+			return "", err
+		}
+		return "", Done
+	})
+}
+
+// ForJSON returns an Iterator over the elements of the JSON array read by dec,
+// decoding them one at a time into values of type T. It returns an error if
+// the input does not start with an array. Decoding and read errors are
+// returned as is; they are sticky, as is Done after the closing bracket.
+func ForJSON[T any](dec *json.Decoder) Iterator[T] {
+	started := false
+	var err error
+	return iteratorFunc[T](func() (T, error) {
+		var zero T
+		if err != nil {
+			return zero, err
+		}
+		if !started {
+			started = true
+			var t json.Token
+			if t, err = dec.Token(); err != nil {
+				return zero, err
+			}
+			if d, ok := t.(json.Delim); !ok || d != '[' {
+				err = fmt.Errorf("expected start of JSON array, got %v", t)
+				return zero, err
+			}
+		}
+		if !dec.More() {
+			if _, err = dec.Token(); err != nil {
+				return zero, err
+			}
+			err = Done
+			return zero, err
+		}
+		var x T
+		if err = dec.Decode(&x); err != nil {
+			return zero, err
+		}
+		return x, nil
+	})
+}
+
+// ForJSONFile opens the file at path and returns an Iterator over the
+// elements of the JSON array it contains (see ForJSON), along with the
+// Closer for the file.
+func ForJSONFile[T any](path string) (Iterator[T], io.Closer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ForJSON[T](json.NewDecoder(f)), f, nil
+}
+
+// ForCSV returns an Iterator over the records read by r, one slice of fields
+// per row. It returns Done at the end of the input; parse and read errors are
+// returned as is. If r.ReuseRecord is set, a slice is only valid until the
+// next call to Next.
+func ForCSV(r *csv.Reader) Iterator[[]string] {
+	return iteratorFunc[[]string](func() ([]string, error) {
+		row, err := r.Read()
+		if err == io.EOF {
+			return nil, Done
+		}
+		if err != nil {
+			return nil, err
+		}
+		return row, nil
+	})
+}
+
+// ForCSVWithHeaders returns an Iterator like ForCSV does, but uses the first
+// row as column headers and returns every subsequent row as a map from header
+// to field.
+func ForCSVWithHeaders(r *csv.Reader) Iterator[map[string]string] {
+	it := ForCSV(r)
+	var headers []string
+	return iteratorFunc[map[string]string](func() (map[string]string, error) {
+		if headers == nil {
+			row, err := it.Next()
+			if err != nil {
+				return nil, err
+			}
+			headers = append([]string{}, row...)
+		}
+		row, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[string]string, len(headers))
+		for i, h := range headers {
+			if i < len(row) {
+				m[h] = row[i]
+			}
+		}
+		return m, nil
+	})
+}
+
+// integer is a constraint for the integer types.
+type integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// float is a constraint for the floating point types.
+type float interface {
+	~float32 | ~float64
+}
+
+// ForRange returns an Iterator over the integers from start up to, but not
+// including, end, in increments of step. A negative step counts down. The
+// iterator is empty if end cannot be reached from start. Panics if step is
+// zero.
+func ForRange[T integer](start, end, step T) Iterator[T] {
+	return forRange(start, end, step, false)
+}
+
+// ForRangeInclusive is like ForRange, but includes end if it is reached.
+func ForRangeInclusive[T integer](start, end, step T) Iterator[T] {
+	return forRange(start, end, step, true)
+}
+
+func forRange[T integer](start, end, step T, inclusive bool) Iterator[T] {
+	if step == 0 {
+		panic("listfilter: step must not be zero")
+	}
+	x, done := start, false
+	return iteratorFunc[T](func() (T, error) {
+		if done || !inRange(x, end, step > 0, inclusive) {
+			done = true
+			var zero T
+			return zero, Done
+		}
+		cur := x
+		x += step
+		// stop at overflow
+		done = (step > 0) != (x > cur)
+		return cur, nil
+	})
+}
+
+// ForRangeFloat returns an Iterator like ForRange does, for floating point
+// numbers. To prevent rounding errors from accumulating, the n-th element is
+// computed as start + n*step. Panics if step is zero.
+func ForRangeFloat[T float](start, end, step T) Iterator[T] {
+	if step == 0 {
+		panic("listfilter: step must not be zero")
+	}
+	n := 0
+	return iteratorFunc[T](func() (T, error) {
+		x := start + T(n)*step
+		if !inRange(x, end, step > 0, false) {
+			return 0, Done
+		}
+		n += 1
+		return x, nil
+	})
+}
+
+// inRange reports whether x has not passed end, counting up or down.
+func inRange[T integer | float](x, end T, up, inclusive bool) bool {
+	if inclusive && x == end {
+		return true
+	}
+	if up {
+		return x < end
+	}
+	return x > end
+}
+
+// ForGenerator returns an Iterator that yields seed, followed by the values
+// generated by calling next on the previous value. The iterator ends with the
+// first error next returns, which is returned as is; next can return Done to
+// end it cleanly. The sequence can be infinite; use Take to bound it.
+func ForGenerator[T any](seed T, next func(T) (T, error)) Iterator[T] {
+	x, started := seed, false
+	var err error
+	return iteratorFunc[T](func() (T, error) {
+		var zero T
+		if err != nil {
+			return zero, err
+		}
+		if started {
+			if x, err = next(x); err != nil {
+				return zero, err
+			}
+		}
+		started = true
+		return x, nil
+	})
+}
+
+// ForRepeat returns an Iterator that yields v n times. It is empty if n is
+// not positive.
+func ForRepeat[T any](v T, n int) Iterator[T] {
+	i := 0
+	return iteratorFunc[T](func() (T, error) {
+		if i >= n {
+			var zero T
+			return zero, Done
+		}
+		i += 1
+		return v, nil
+	})
+}
+
+// ForCycle returns an Iterator that cycles through the elements of xs,
+// starting over after the last one. It never ends; use Take to bound it.
+// Panics if xs is empty.
+func ForCycle[T any](xs []T) Iterator[T] {
+	if len(xs) == 0 {
+		panic("listfilter: cannot cycle through an empty slice")
+	}
+	i := 0
+	return iteratorFunc[T](func() (T, error) {
+		x := xs[i]
+		i = (i + 1) % len(xs)
+		return x, nil
+	})
+}
+
+// ForChannel returns an Iterator over the values received from ch. Next blocks
+// until a value is received and returns Done once ch is closed.
+func ForChannel[T any](ch <-chan T) Iterator[T] {
+	return ForChannelWithCancel(ch, nil)
+}
+
+// ForChannelWithCancel returns an Iterator like ForChannel does, which
+// returns Canceled once done is closed, even if ch has values left.
+func ForChannelWithCancel[T any](ch <-chan T, done <-chan struct{}) Iterator[T] {
+	return iteratorFunc[T](func() (T, error) {
+		var zero T
+		select {
+		case <-done:
+			return zero, Canceled
+		default:
+		}
+		select {
+		case x, ok := <-ch:
+			if !ok {
+				return zero, Done
+			}
+			return x, nil
+		case <-done:
+			return zero, Canceled
+		}
+	})
+}
+
+// Map returns an Iterator that lazily applies fn to every element of it.
+// Errors, including Done, are passed on unchanged. A panic in fn is recovered
+// and returned as an error.
+func Map[T, U any](it Iterator[T], fn func(T) U) Iterator[U] {
+	return iteratorFunc[U](func() (u U, err error) {
+		x, err := it.Next()
+		if err != nil {
+			return u, err
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic in map function: %v", r)
+			}
+		}()
+		return fn(x), nil
+	})
+}
+
+// Peek returns an Iterator that calls fn for every element of it, in the
+// goroutine calling Next, before passing it on unchanged. A panic in fn is
+// recovered and returned as an error.
+func Peek[T any](it Iterator[T], fn func(T)) Iterator[T] {
+	return iteratorFunc[T](func() (x T, err error) {
+		x, err = it.Next()
+		if err != nil {
+			return x, err
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				var zero T
+				x, err = zero, fmt.Errorf("panic in peek function: %v", r)
+			}
+		}()
+		fn(x)
+		return x, nil
+	})
+}
+
+// FlatMap returns an Iterator that yields the elements of the iterators that
+// fn returns for every element of it, one iterator after the other. A nil
+// iterator is treated as empty. Errors other than Done, from it or from the
+// iterators fn returns, are passed on immediately.
+func FlatMap[T, U any](it Iterator[T], fn func(T) Iterator[U]) Iterator[U] {
+	var inner Iterator[U]
+	return iteratorFunc[U](func() (U, error) {
+		for {
+			if inner != nil {
+				x, err := inner.Next()
+				if err != Done {
+					return x, err
+				}
+				inner = nil
+			}
+			x, err := it.Next()
+			if err != nil {
+				var zero U
+				return zero, err
+			}
+			inner = fn(x)
+		}
+	})
+}
+
+// FilterIter returns an Iterator that only yields the elements of it for which
+// pred returns true.
+func FilterIter[T any](it Iterator[T], pred func(T) bool) Iterator[T] {
+	return iteratorFunc[T](func() (T, error) {
+		for {
+			x, err := it.Next()
+			if err != nil || pred(x) {
+				return x, err
+			}
+		}
+	})
+}
+
+// Reduce folds the elements of it into a single value, starting with initial.
+// The iterator is consumed entirely. If it returns an error other than Done,
+// the value accumulated so far is returned along with the error.
+func Reduce[T, U any](it Iterator[T], fn func(U, T) U, initial U) (U, error) {
+	acc := initial
+	for {
+		x, err := it.Next()
+		if err == Done {
+			return acc, nil
+		}
+		if err != nil {
+			return acc, err
+		}
+		acc = fn(acc, x)
+	}
+}
+
+// Take returns an Iterator that yields at most n elements of it. The source
+// is not read beyond the n-th element, so any resources it holds should be
+// released by the caller. If n is not positive, the iterator is done
+// immediately.
+func Take[T any](it Iterator[T], n int) Iterator[T] {
+	return iteratorFunc[T](func() (T, error) {
+		if n <= 0 {
+			var zero T
+			return zero, Done
+		}
+		n -= 1
+		return it.Next()
+	})
+}
+
+// Skip returns an Iterator that discards the first n elements of it and
+// yields the rest. The elements are skipped lazily, on the first call to
+// Next. If it has fewer than n elements, the iterator is done immediately.
+func Skip[T any](it Iterator[T], n int) Iterator[T] {
+	return iteratorFunc[T](func() (T, error) {
+		for ; n > 0; n -= 1 {
+			if x, err := it.Next(); err != nil {
+				return x, err
+			}
+		}
+		return it.Next()
+	})
+}
+
+// A ZipPair holds an element of each of the iterators passed to Zip.
+type ZipPair[T, U any] struct {
+	First  T
+	Second U
+}
+
+// Zip returns an Iterator that pairs the elements of it1 and it2, until either
+// returns Done. Other errors are passed on.
+func Zip[T, U any](it1 Iterator[T], it2 Iterator[U]) Iterator[ZipPair[T, U]] {
+	return iteratorFunc[ZipPair[T, U]](func() (ZipPair[T, U], error) {
+		x, err := it1.Next()
+		if err != nil {
+			return ZipPair[T, U]{}, err
+		}
+		y, err := it2.Next()
+		if err != nil {
+			return ZipPair[T, U]{}, err
+		}
+		return ZipPair[T, U]{x, y}, nil
+	})
+}
+
+// An IndexedValue holds an element and its position, as yielded by
+// Enumerate.
+type IndexedValue[T any] struct {
+	Index int
+	Value T
+}
+
+// Enumerate returns an Iterator that pairs the elements of it with their
+// index, starting at 0.
+func Enumerate[T any](it Iterator[T]) Iterator[IndexedValue[T]] {
+	i := 0
+	return iteratorFunc[IndexedValue[T]](func() (IndexedValue[T], error) {
+		x, err := it.Next()
+		if err != nil {
+			return IndexedValue[T]{}, err
+		}
+		i += 1
+		return IndexedValue[T]{i - 1, x}, nil
+	})
+}
+
+// Chain returns an Iterator that yields the elements of the iterators in
+// turn, moving to the next iterator when one returns Done. Other errors are
+// passed on immediately.
+func Chain[T any](iterators ...Iterator[T]) Iterator[T] {
+	return iteratorFunc[T](func() (T, error) {
+		for len(iterators) > 0 {
+			x, err := iterators[0].Next()
+			if err != Done {
+				return x, err
+			}
+			iterators = iterators[1:]
+		}
+		var zero T
+		return zero, Done
+	})
+}
+
+// iteratorResult holds the return values of an Iterator's Next method.
+type iteratorResult[T any] struct {
+	x   T
+	err error
+}
+
+// Merge returns an Iterator that yields the elements of the iterators as they
+// become available, reading each of them in its own goroutine. The order of
+// the elements is therefore not deterministic, other than that the elements
+// of each iterator stay in order. The first error other than Done is returned
+// and ends the merge; goroutines blocked on their iterator's Next stop after
+// it returns. Done is returned once all iterators are done. The merged
+// iterator should be read until it returns an error, or goroutines will
+// leak.
+func Merge[T any](iterators ...Iterator[T]) Iterator[T] {
+	ch := make(chan iteratorResult[T])
+	stop := make(chan struct{})
+	var start sync.Once
+	var err error
+	remaining := len(iterators)
+	return iteratorFunc[T](func() (T, error) {
+		var zero T
+		if err != nil {
+			return zero, err
+		}
+		start.Do(func() {
+			for _, it := range iterators {
+				go func(it Iterator[T]) {
+					for {
+						x, err := it.Next()
+						select {
+						case ch <- iteratorResult[T]{x, err}:
+						case <-stop:
+							return
+						}
+						if err != nil {
+							return
+						}
+					}
+				}(it)
+			}
+		})
+		for remaining > 0 {
+			r := <-ch
+			if r.err == Done {
+				remaining -= 1
+				continue
+			}
+			if r.err != nil {
+				err = r.err
+				close(stop)
+				return zero, err
+			}
+			return r.x, nil
+		}
+		err = Done
+		return zero, err
+	})
+}
+
+// RoundRobin returns an Iterator that yields an element of each of the
+// iterators in turn, in the order given. An iterator is left out once it is
+// done. Unlike Merge, it reads the iterators in the goroutine calling Next,
+// so the order of the elements is deterministic. The first error other than
+// Done is returned and ends the iteration; Done is returned once all
+// iterators are done.
+func RoundRobin[T any](iterators ...Iterator[T]) Iterator[T] {
+	its := append([]Iterator[T]{}, iterators...)
+	i := 0
+	var err error
+	return iteratorFunc[T](func() (T, error) {
+		var zero T
+		for err == nil && len(its) > 0 {
+			x, e := its[i].Next()
+			if e == Done {
+				its = append(its[:i], its[i+1:]...)
+				if i >= len(its) {
+					i = 0
+				}
+				continue
+			}
+			if e != nil {
+				err = e
+				break
+			}
+			i = (i + 1) % len(its)
+			return x, nil
+		}
+		if err != nil {
+			return zero, err
+		}
+		return zero, Done
+	})
+}
+
+// Buffered returns an Iterator that reads ahead up to size elements of it in a
+// background goroutine, so that reading from it overlaps with processing the
+// elements. The goroutine starts with the first call to Next. Errors are
+// passed on after the elements read before them. Buffered panics if size is
+// not positive.
+//
+// The returned Iterator also implements io.Closer. It should be closed if it
+// is not read until it returns an error, to stop the goroutine.
+func Buffered[T any](it Iterator[T], size int) Iterator[T] {
+	if size <= 0 {
+		panic("listfilter: buffer size must be positive")
+	}
+	return &bufferedIterator[T]{
+		it:   it,
+		ch:   make(chan iteratorResult[T], size-1),
+		stop: make(chan struct{}),
+	}
+}
+
+// A bufferedIterator is an Iterator that reads ahead. See Buffered.
+type bufferedIterator[T any] struct {
+	it    Iterator[T]
+	ch    chan iteratorResult[T]
+	stop  chan struct{}
+	start sync.Once
+	once  sync.Once
+	err   error
+}
+
+func (b *bufferedIterator[T]) Next() (T, error) {
+	var zero T
+	if b.err != nil {
+		return zero, b.err
+	}
+	b.start.Do(b.run)
+	r, ok := <-b.ch
+	if !ok {
+		// stopped
+		b.err = Done
+		return zero, b.err
+	}
+	if r.err != nil {
+		b.err = r.err
+		return zero, b.err
+	}
+	return r.x, nil
+}
+
+// run starts reading ahead.
+func (b *bufferedIterator[T]) run() {
+	go func() {
+		defer close(b.ch)
+		for {
+			x, err := b.it.Next()
+			select {
+			case b.ch <- iteratorResult[T]{x, err}:
+			case <-b.stop:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+}
+
+// Close stops reading ahead. The background goroutine ends once the Next
+// call it might be waiting for returns. Subsequent calls to Next return Done,
+// unless an error was returned before. Close should not be called
+// concurrently with Next. It always returns nil.
+func (b *bufferedIterator[T]) Close() error {
+	b.once.Do(func() {
+		close(b.stop)
+		if b.err == nil {
+			b.err = Done
+		}
+	})
+	return nil
+}
+
+// WithTimeout returns an Iterator that returns Timeout when it does not
+// return within d. Each call to Next gets a fresh timeout. After a timeout,
+// the next call continues waiting for the pending element, so no elements
+// are lost and it is never read concurrently.
+func WithTimeout[T any](it Iterator[T], d time.Duration) Iterator[T] {
+	var pending chan iteratorResult[T]
+	return iteratorFunc[T](func() (T, error) {
+		if pending == nil {
+			pending = make(chan iteratorResult[T], 1)
+			go func(ch chan<- iteratorResult[T]) {
+				x, err := it.Next()
+				ch <- iteratorResult[T]{x, err}
+			}(pending)
+		}
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case r := <-pending:
+			pending = nil
+			return r.x, r.err
+		case <-timer.C:
+			var zero T
+			return zero, Timeout
+		}
+	})
+}
+
+// Tee returns n iterators that each yield all elements of it. Elements are
+// buffered until every iterator has read them, so the buffer grows without
+// bound when one of the iterators lags behind. The iterators may be read from
+// different goroutines.
+func Tee[T any](it Iterator[T], n int) []Iterator[T] {
+	return TeeWithBuffer(it, n, 0)
+}
+
+// TeeWithBuffer is like Tee, but buffers at most size elements. An iterator
+// that gets ahead of the others by that many elements blocks until the
+// slowest iterator catches up. Therefore, the iterators should be read from
+// different goroutines and all of them should be read to the end. A size of
+// zero or less means no limit.
+func TeeWithBuffer[T any](it Iterator[T], n, size int) []Iterator[T] {
+	if n <= 0 {
+		return nil
+	}
+	t := &tee[T]{src: it, pos: make([]int, n), size: size}
+	t.cond = sync.NewCond(&t.mu)
+	its := make([]Iterator[T], n)
+	for i := range its {
+		i := i
+		its[i] = iteratorFunc[T](func() (T, error) {
+			return t.next(i)
+		})
+	}
+	return its
+}
+
+// tee holds the state shared by the iterators returned by TeeWithBuffer.
+type tee[T any] struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	src   Iterator[T]
+	buf   []T
+	start int
+	pos   []int
+	err   error
+	size  int
+}
+
+// next returns the next element for the i-th iterator. Positions are
+// absolute; buf[0] is the element at position start.
+func (t *tee[T]) next(i int) (T, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for {
+		if p := t.pos[i]; p < t.start+len(t.buf) {
+			x := t.buf[p-t.start]
+			t.pos[i] += 1
+			t.trim()
+			return x, nil
+		}
+		if t.err != nil {
+			var zero T
+			return zero, t.err
+		}
+		if t.size > 0 && len(t.buf) >= t.size {
+			t.cond.Wait()
+			continue
+		}
+		x, err := t.src.Next()
+		if err != nil {
+			t.err = err
+			continue
+		}
+		t.buf = append(t.buf, x)
+	}
+}
+
+// trim drops the elements that every iterator has read.
+func (t *tee[T]) trim() {
+	lowest := t.pos[0]
+	for _, p := range t.pos[1:] {
+		if p < lowest {
+			lowest = p
+		}
+	}
+	if lowest == t.start {
+		return
+	}
+	var zero T
+	for j := 0; j < lowest-t.start; j += 1 {
+		t.buf[j] = zero
+	}
+	t.buf = t.buf[lowest-t.start:]
+	t.start = lowest
+	t.cond.Broadcast()
+}
+
+// Batch returns an Iterator that groups the elements of it into slices of
+// length size. The last slice may be shorter. If it returns an error, the
+// elements read before it are yielded first. Every slice is newly allocated.
+// Batch panics if size is not positive.
+func Batch[T any](it Iterator[T], size int) Iterator[[]T] {
+	if size <= 0 {
+		panic("listfilter: batch size must be positive")
+	}
+	var err error
+	return iteratorFunc[[]T](func() ([]T, error) {
+		if err != nil {
+			return nil, err
+		}
+		var xs []T
+		for len(xs) < size {
+			var x T
+			if x, err = it.Next(); err != nil {
+				break
+			}
+			xs = append(xs, x)
+		}
+		if len(xs) == 0 {
+			return nil, err
+		}
+		return xs, nil
+	})
+}
+
+// Window returns an Iterator over the overlapping windows of size consecutive
+// elements of it: [1 2 3 4] with size 2 results in [1 2], [2 3] and [3 4].
+// It ends when it does, without returning the remaining elements if there
+// are fewer than size. Every window is a new slice. Window panics if size is
+// not positive.
+func Window[T any](it Iterator[T], size int) Iterator[[]T] {
+	if size <= 0 {
+		panic("listfilter: window size must be positive")
+	}
+	var xs []T
+	var err error
+	return iteratorFunc[[]T](func() ([]T, error) {
+		if err != nil {
+			return nil, err
+		}
+		if len(xs) == size {
+			xs = xs[1:]
+		}
+		for len(xs) < size {
+			var x T
+			if x, err = it.Next(); err != nil {
+				return nil, err
+			}
+			xs = append(xs, x)
+		}
+		w := make([]T, size)
+		copy(w, xs)
+		return w, nil
+	})
+}
+
+// Distinct returns an Iterator that only yields the first occurrence of every
+// element of it. All distinct elements are kept in memory.
+func Distinct[T comparable](it Iterator[T]) Iterator[T] {
+	return DistinctBy(it, func(x T) T { return x })
+}
+
+// DistinctBy returns an Iterator that only yields the first element of it for
+// every distinct key, as computed by key. All distinct keys are kept in
+// memory.
+func DistinctBy[T any, K comparable](it Iterator[T], key func(T) K) Iterator[T] {
+	seen := make(map[K]bool)
+	return FilterIter(it, func(x T) bool {
+		k := key(x)
+		if seen[k] {
+			return false
+		}
+		seen[k] = true
+		return true
+	})
+}
+
+// Sort returns an Iterator over the elements of it, sorted by less. The sort
+// is stable. As sorting requires all elements, it is read to the end and its
+// elements are kept in memory; this happens on the first call to Next. If it
+// returns an error other than Done, that error is returned instead.
+func Sort[T any](it Iterator[T], less func(T, T) bool) Iterator[T] {
+	var sorted Iterator[T]
+	return iteratorFunc[T](func() (T, error) {
+		if sorted == nil {
+			xs, err := readAll(it)
+			if err != nil {
+				var zero T
+				return zero, err
+			}
+			sort.SliceStable(xs, func(i, j int) bool { return less(xs[i], xs[j]) })
+			sorted = ForSlice(xs)
+		}
+		return sorted.Next()
+	})
+}
+
+// ToSlice reads all elements of it into a slice. For an empty iterator, an
+// empty (not nil) slice is returned. If it returns an error other than Done,
+// the elements read so far are returned along with the error.
+func ToSlice[T any](it Iterator[T]) ([]T, error) {
+	xs, err := readAll(it)
+	if xs == nil {
+		xs = []T{}
+	}
+	return xs, err
+}
+
+// readAll reads all elements from the iterator.
+func readAll[T any](it Iterator[T]) ([]T, error) {
+	var xs []T
+	for {
+		x, err := it.Next()
+		if err == Done {
+			return xs, nil
+		}
+		if err != nil {
+			return xs, err
+		}
+		xs = append(xs, x)
+	}
+}
+
+// An Entry is a key-value pair.
+type Entry[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// ToMap reads all elements of it into a map, using key to compute their keys.
+// Later elements overwrite earlier ones with the same key. If it returns an
+// error other than Done, the map built so far is returned along with the
+// error.
+func ToMap[K comparable, V any](it Iterator[V], key func(V) K) (map[K]V, error) {
+	return Reduce(it, func(m map[K]V, x V) map[K]V {
+		m[key(x)] = x
+		return m
+	}, make(map[K]V))
+}
+
+// CollectMap reads all entries of it into a map. Later entries overwrite
+// earlier ones with the same key. If it returns an error other than Done, the
+// map built so far is returned along with the error.
+func CollectMap[K comparable, V any](it Iterator[Entry[K, V]]) (map[K]V, error) {
+	return Reduce(it, func(m map[K]V, e Entry[K, V]) map[K]V {
+		m[e.Key] = e.Value
+		return m
+	}, make(map[K]V))
+}
+
+// Count reads all elements of it and returns their number. If it returns an
+// error other than Done, the number read so far is returned along with the
+// error.
+func Count[T any](it Iterator[T]) (int, error) {
+	return Reduce(it, func(n int, _ T) int { return n + 1 }, 0)
+}
+
+// First returns the first element of it. Only one element is read, so the
+// iterator can be used to read the remaining elements. If it is empty, the
+// zero value and false are returned.
+func First[T any](it Iterator[T]) (T, bool, error) {
+	var zero T
+	x, err := it.Next()
+	if err == Done {
+		return zero, false, nil
+	}
+	if err != nil {
+		return zero, false, err
+	}
+	return x, true, nil
+}
+
+// Last reads all elements of it and returns the last one. If it is empty, the
+// zero value and false are returned.
+func Last[T any](it Iterator[T]) (T, bool, error) {
+	var last T
+	found := false
+	for {
+		x, err := it.Next()
+		if err == Done {
+			return last, found, nil
+		}
+		if err != nil {
+			var zero T
+			return zero, false, err
+		}
+		last, found = x, true
+	}
+}
+
+// Any reports whether pred holds for any element of it. Iteration stops at the
+// first element for which it does.
+func Any[T any](it Iterator[T], pred func(T) bool) (bool, error) {
+	for {
+		x, err := it.Next()
+		if err == Done {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if pred(x) {
+			return true, nil
+		}
+	}
+}
+
+// All reports whether pred holds for all elements of it. Iteration stops at
+// the first element for which it does not. All returns true for an empty
+// iterator.
+func All[T any](it Iterator[T], pred func(T) bool) (bool, error) {
+	ok, err := Any(it, func(x T) bool { return !pred(x) })
+	return !ok && err == nil, err
+}
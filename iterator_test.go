@@ -0,0 +1,1504 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// errIterator returns the elements of xs, followed by err.
+func errIterator[T any](err error, xs ...T) Iterator[T] {
+	it := ForSlice(xs)
+	return iteratorFunc[T](func() (T, error) {
+		x, e := it.Next()
+		if e == Done {
+			return x, err
+		}
+		return x, e
+	})
+}
+
+var errTest = errors.New("test error")
+
+func TestForSlice(t *testing.T) {
+	tests := []struct {
+		name string
+		xs   []int
+	}{
+		{"nil", nil},
+		{"empty", []int{}},
+		{"some", []int{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it := ForSlice(tt.xs)
+			got, err := readAll(it)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tt.xs) || (len(got) > 0 && !reflect.DeepEqual(got, tt.xs)) {
+				t.Errorf("ForSlice() got = %v, want %v", got, tt.xs)
+			}
+			if _, err := it.Next(); err != Done {
+				t.Errorf("Next() after end got = %v, want Done", err)
+			}
+		})
+	}
+}
+
+func TestForLines(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"single", "foo", []string{"foo"}},
+		{"trailing newline", "foo\nbar\n", []string{"foo", "bar"}},
+		{"carriage returns", "foo\r\nbar\r\n", []string{"foo", "bar"}},
+		{"empty lines", "\nfoo\n\n", []string{"", "foo", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it := ForLines(strings.NewReader(tt.s))
+			got, err := readAll(it)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ForLines() got = %q, want %q", got, tt.want)
+			}
+			if _, err := it.Next(); err != Done {
+				t.Errorf("Next() after end got = %v, want Done", err)
+			}
+		})
+	}
+}
+
+func TestForLines_errors(t *testing.T) {
+	long := strings.Repeat("x", bufio.MaxScanTokenSize+1)
+	if _, err := readAll(ForLines(strings.NewReader(long))); err != bufio.ErrTooLong {
+		t.Errorf("ForLines() error = %v, want %v", err, bufio.ErrTooLong)
+	}
+	got, err := readAll(ForLinesWithBuffer(strings.NewReader(long+"\nfoo"), len(long)+1))
+	if err != nil || len(got) != 2 || got[0] != long || got[1] != "foo" {
+		t.Errorf("ForLinesWithBuffer() got %d lines, error = %v, want 2 lines", len(got), err)
+	}
+	if _, err := readAll(ForLinesWithBuffer(strings.NewReader("foo\nbar"), 2)); err != bufio.ErrTooLong {
+		t.Errorf("ForLinesWithBuffer() error = %v, want %v", err, bufio.ErrTooLong)
+	}
+	r := io.MultiReader(strings.NewReader("foo\n"), iotest.ErrReader(errTest))
+	got, err = readAll(ForLines(r))
+	if err != errTest || !reflect.DeepEqual(got, []string{"foo"}) {
+		t.Errorf("ForLines() got = %q, %v, want [foo], %v", got, err, errTest)
+	}
+}
+
+func TestForLinesWithBuffer_panic(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("ForLinesWithBuffer() with size %d did not panic", n)
+				}
+			}()
+			ForLinesWithBuffer(strings.NewReader("foo"), n)
+		}()
+	}
+}
+
+func TestForJSON(t *testing.T) {
+	type item struct {
+		Name string `json:"name"`
+	}
+	tests := []struct {
+		name    string
+		s       string
+		want    []item
+		wantErr bool
+	}{
+		{"empty", "[]", nil, false},
+		{"some", `[{"name": "foo"}, {"name": "bar"}]`, []item{{"foo"}, {"bar"}}, false},
+		{"whitespace", " [\n{\"name\":\"foo\"}\n]\n", []item{{"foo"}}, false},
+		{"not an array", `{"name": "foo"}`, nil, true},
+		{"bad element", `[{"name": "foo"}, {"name": 1}]`, []item{{"foo"}}, true},
+		{"truncated", `[{"name": "foo"}`, []item{{"foo"}}, true},
+		{"no input", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it := ForJSON[item](json.NewDecoder(strings.NewReader(tt.s)))
+			got, err := readAll(it)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ForJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ForJSON() got = %v, want %v", got, tt.want)
+			}
+			if _, err2 := it.Next(); (tt.wantErr && err2 != err) || (!tt.wantErr && err2 != Done) {
+				t.Errorf("Next() after end got = %v", err2)
+			}
+		})
+	}
+}
+
+func TestForJSONFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "xs.json")
+	if err := os.WriteFile(path, []byte("[1, 2, 3]"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	it, closer, err := ForJSONFile[int](path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer closer.Close()
+	got, err := readAll(it)
+	if err != nil || !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("ForJSONFile() got = %v, %v, want [1 2 3]", got, err)
+	}
+	if _, _, err := ForJSONFile[int](filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("expected error for missing file")
+	}
+}
+
+func TestForCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    [][]string
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"some", "a,b\n1,2\n", [][]string{{"a", "b"}, {"1", "2"}}, false},
+		{"quoted", "\"a,b\",c\n", [][]string{{"a,b", "c"}}, false},
+		{"field count", "a,b\n1\n", [][]string{{"a", "b"}}, true},
+		{"bare quote", "a,b\"\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readAll(ForCSV(csv.NewReader(strings.NewReader(tt.s))))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ForCSV() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ForCSV() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForCSVWithHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    []map[string]string
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"headers only", "a,b\n", nil, false},
+		{"some", "a,b\n1,2\n3,4\n", []map[string]string{{"a": "1", "b": "2"}, {"a": "3", "b": "4"}}, false},
+		{"field count", "a,b\n1,2\n3\n", []map[string]string{{"a": "1", "b": "2"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := csv.NewReader(strings.NewReader(tt.s))
+			r.ReuseRecord = true
+			got, err := readAll(ForCSVWithHeaders(r))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ForCSVWithHeaders() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ForCSVWithHeaders() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForRange(t *testing.T) {
+	tests := []struct {
+		name                string
+		start, end, step    int
+		want, wantInclusive []int
+	}{
+		{"up", 0, 5, 2, []int{0, 2, 4}, []int{0, 2, 4}},
+		{"up to end", 0, 4, 2, []int{0, 2}, []int{0, 2, 4}},
+		{"down", 3, 0, -1, []int{3, 2, 1}, []int{3, 2, 1, 0}},
+		{"start is end", 1, 1, 1, nil, []int{1}},
+		{"unreachable", 5, 0, 1, nil, nil},
+		{"unreachable down", 0, 5, -1, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readAll(ForRange(tt.start, tt.end, tt.step))
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ForRange() got = %v, %v, want %v", got, err, tt.want)
+			}
+			got, err = readAll(ForRangeInclusive(tt.start, tt.end, tt.step))
+			if err != nil || !reflect.DeepEqual(got, tt.wantInclusive) {
+				t.Errorf("ForRangeInclusive() got = %v, %v, want %v", got, err, tt.wantInclusive)
+			}
+		})
+	}
+}
+
+func TestForRange_overflow(t *testing.T) {
+	got, err := readAll(ForRangeInclusive[uint8](250, 255, 2))
+	if want := []uint8{250, 252, 254}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ForRangeInclusive() got = %v, %v, want %v", got, err, want)
+	}
+	got, err = readAll(ForRangeInclusive[uint8](253, 255, 1))
+	if want := []uint8{253, 254, 255}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ForRangeInclusive() got = %v, %v, want %v", got, err, want)
+	}
+	down, err := readAll(ForRangeInclusive[int8](-126, -128, -1))
+	if want := []int8{-126, -127, -128}; err != nil || !reflect.DeepEqual(down, want) {
+		t.Errorf("ForRangeInclusive() got = %v, %v, want %v", down, err, want)
+	}
+}
+
+func TestForRangeFloat(t *testing.T) {
+	got, err := readAll(ForRangeFloat(0, 1, 0.1))
+	if err != nil || len(got) != 10 || got[3] != 0.30000000000000004 || got[9] != 0.9 {
+		t.Errorf("ForRangeFloat() got = %v, %v", got, err)
+	}
+	got, err = readAll(ForRangeFloat(1, 0, -0.5))
+	if want := []float64{1, 0.5}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ForRangeFloat() got = %v, %v, want %v", got, err, want)
+	}
+}
+
+func TestForRange_panic(t *testing.T) {
+	for name, f := range map[string]func(){
+		"ForRange":          func() { ForRange(0, 1, 0) },
+		"ForRangeInclusive": func() { ForRangeInclusive(0, 1, 0) },
+		"ForRangeFloat":     func() { ForRangeFloat(0, 1, 0.0) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s() with step 0 did not panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestForGenerator(t *testing.T) {
+	type pair struct{ a, b int }
+	fib := func(p pair) (pair, error) { return pair{p.b, p.a + p.b}, nil }
+	got, err := readAll(Map(Take(ForGenerator(pair{0, 1}, fib), 8), func(p pair) int { return p.a }))
+	if want := []int{0, 1, 1, 2, 3, 5, 8, 13}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ForGenerator() got = %v, %v, want %v", got, err, want)
+	}
+
+	pages := map[string]string{"": "a", "a": "b"}
+	calls := 0
+	it := ForGenerator("", func(token string) (string, error) {
+		calls += 1
+		if next, ok := pages[token]; ok {
+			return next, nil
+		}
+		return "", Done
+	})
+	tokens, err := readAll(it)
+	if want := []string{"", "a", "b"}; err != nil || !reflect.DeepEqual(tokens, want) {
+		t.Errorf("ForGenerator() got = %q, %v, want %q", tokens, err, want)
+	}
+	if _, err := it.Next(); err != Done || calls != 3 {
+		t.Errorf("Next() after end got = %v after %d calls, want Done after 3", err, calls)
+	}
+
+	it = ForGenerator("x", func(string) (string, error) { return "", errTest })
+	tokens, err = readAll(it)
+	if err != errTest || !reflect.DeepEqual(tokens, []string{"x"}) {
+		t.Errorf("ForGenerator() got = %q, %v, want [x], %v", tokens, err, errTest)
+	}
+}
+
+func TestForRepeat(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		want []string
+	}{
+		{"negative", -1, nil},
+		{"zero", 0, nil},
+		{"some", 3, []string{"x", "x", "x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it := ForRepeat("x", tt.n)
+			got, err := readAll(it)
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ForRepeat() got = %v, %v, want %v", got, err, tt.want)
+			}
+			if _, err := it.Next(); err != Done {
+				t.Errorf("Next() after end got = %v, want Done", err)
+			}
+		})
+	}
+}
+
+func TestForCycle(t *testing.T) {
+	got, err := readAll(Take(ForCycle([]int{1, 2, 3}), 7))
+	if want := []int{1, 2, 3, 1, 2, 3, 1}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ForCycle() got = %v, %v, want %v", got, err, want)
+	}
+	got, err = readAll(Take(ForCycle([]int{1}), 3))
+	if want := []int{1, 1, 1}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ForCycle() got = %v, %v, want %v", got, err, want)
+	}
+}
+
+func TestForCycle_panic(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("ForCycle() with empty slice did not panic")
+		}
+	}()
+	ForCycle([]int{})
+}
+
+func TestForChannel(t *testing.T) {
+	tests := []struct {
+		name string
+		xs   []int
+	}{
+		{"empty", nil},
+		{"some", []int{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := make(chan int)
+			go func() {
+				defer close(ch)
+				for _, x := range tt.xs {
+					ch <- x
+				}
+			}()
+			it := ForChannel(ch)
+			got, err := readAll(it)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tt.xs) || (len(got) > 0 && !reflect.DeepEqual(got, tt.xs)) {
+				t.Errorf("ForChannel() got = %v, want %v", got, tt.xs)
+			}
+			if _, err := it.Next(); err != Done {
+				t.Errorf("Next() after end got = %v, want Done", err)
+			}
+		})
+	}
+}
+
+func TestForChannelWithCancel(t *testing.T) {
+	ch := make(chan int, 2)
+	ch <- 1
+	ch <- 2
+	done := make(chan struct{})
+	it := ForChannelWithCancel(ch, done)
+	if x, err := it.Next(); err != nil || x != 1 {
+		t.Fatalf("Next() got = %v, %v, want 1", x, err)
+	}
+	close(done)
+	if _, err := it.Next(); err != Canceled {
+		t.Errorf("Next() after cancel got = %v, want Canceled", err)
+	}
+
+	// cancel while blocked
+	done = make(chan struct{})
+	it = ForChannelWithCancel(make(chan int), done)
+	go close(done)
+	if _, err := it.Next(); err != Canceled {
+		t.Errorf("Next() on blocked channel got = %v, want Canceled", err)
+	}
+}
+
+func TestMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		it      Iterator[int]
+		fn      func(int) string
+		want    []string
+		wantErr error
+	}{
+		{"empty", ForSlice[int](nil), strconv.Itoa, nil, nil},
+		{"some", ForSlice([]int{1, 2, 3}), strconv.Itoa, []string{"1", "2", "3"}, nil},
+		{"error", errIterator(errTest, 1, 2), strconv.Itoa, []string{"1", "2"}, errTest},
+		{
+			"panic",
+			ForSlice([]int{1, 0, 2}),
+			func(i int) string { return strconv.Itoa(10 / i) },
+			[]string{"10"},
+			errors.New("panic"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readAll(Map(tt.it, tt.fn))
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Fatalf("Map() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr == errTest && err != errTest {
+				t.Errorf("Map() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Map() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPeek(t *testing.T) {
+	tests := []struct {
+		name     string
+		it       Iterator[int]
+		want     []int
+		wantSeen []int
+		wantErr  error
+	}{
+		{"empty", ForSlice[int](nil), nil, nil, nil},
+		{"some", ForSlice([]int{1, 2, 3}), []int{1, 2, 3}, []int{1, 2, 3}, nil},
+		{"error", errIterator(errTest, 1, 2), []int{1, 2}, []int{1, 2}, errTest},
+		{"panic", ForSlice([]int{1, 0, 2}), []int{1}, []int{1, 0}, errors.New("panic")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen []int
+			got, err := readAll(Peek(tt.it, func(i int) {
+				seen = append(seen, i)
+				_ = 10 / i
+			}))
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Fatalf("Peek() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr == errTest && err != errTest {
+				t.Errorf("Peek() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Peek() got = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(seen, tt.wantSeen) {
+				t.Errorf("Peek() saw %v, want %v", seen, tt.wantSeen)
+			}
+		})
+	}
+}
+
+func TestFlatMap(t *testing.T) {
+	repeat := func(i int) Iterator[int] {
+		xs := make([]int, i)
+		for j := range xs {
+			xs[j] = i
+		}
+		return ForSlice(xs)
+	}
+	tests := []struct {
+		name    string
+		it      Iterator[int]
+		fn      func(int) Iterator[int]
+		want    []int
+		wantErr error
+	}{
+		{"empty", ForSlice[int](nil), repeat, nil, nil},
+		{"some", ForSlice([]int{1, 0, 2, 3}), repeat, []int{1, 2, 2, 3, 3, 3}, nil},
+		{"nil inner", ForSlice([]int{1, 2}), func(int) Iterator[int] { return nil }, nil, nil},
+		{"outer error", errIterator(errTest, 1, 2), repeat, []int{1, 2, 2}, errTest},
+		{
+			"inner error",
+			ForSlice([]int{1, 2}),
+			func(i int) Iterator[int] { return errIterator(errTest, i) },
+			[]int{1},
+			errTest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readAll(FlatMap(tt.it, tt.fn))
+			if err != tt.wantErr {
+				t.Fatalf("FlatMap() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FlatMap() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterIter(t *testing.T) {
+	even := func(i int) bool { return i%2 == 0 }
+	tests := []struct {
+		name    string
+		it      Iterator[int]
+		want    []int
+		wantErr error
+	}{
+		{"empty", ForSlice[int](nil), nil, nil},
+		{"none match", ForSlice([]int{1, 3}), nil, nil},
+		{"some match", ForSlice([]int{1, 2, 3, 4, 5}), []int{2, 4}, nil},
+		{"all match", ForSlice([]int{2, 4}), []int{2, 4}, nil},
+		{"error", errIterator(errTest, 1, 2, 3), []int{2}, errTest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readAll(FilterIter(tt.it, even))
+			if err != tt.wantErr {
+				t.Fatalf("FilterIter() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterIter() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReduce(t *testing.T) {
+	sum := func(acc, i int) int { return acc + i }
+	tests := []struct {
+		name    string
+		it      Iterator[int]
+		initial int
+		want    int
+		wantErr error
+	}{
+		{"empty", ForSlice[int](nil), 42, 42, nil},
+		{"some", ForSlice([]int{1, 2, 3}), 0, 6, nil},
+		{"initial", ForSlice([]int{1, 2, 3}), 10, 16, nil},
+		{"error", errIterator(errTest, 1, 2), 0, 3, errTest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Reduce(tt.it, sum, tt.initial)
+			if err != tt.wantErr {
+				t.Fatalf("Reduce() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Reduce() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// countingIterator counts the calls to Next.
+type countingIterator[T any] struct {
+	it    Iterator[T]
+	calls int
+}
+
+func (c *countingIterator[T]) Next() (T, error) {
+	c.calls += 1
+	return c.it.Next()
+}
+
+func TestTake(t *testing.T) {
+	tests := []struct {
+		name      string
+		xs        []int
+		n         int
+		want      []int
+		wantCalls int
+	}{
+		{"empty", nil, 2, nil, 1},
+		{"fewer", []int{1}, 2, []int{1}, 2},
+		{"exact", []int{1, 2}, 2, []int{1, 2}, 2},
+		{"more", []int{1, 2, 3, 4}, 2, []int{1, 2}, 2},
+		{"zero", []int{1, 2}, 0, nil, 0},
+		{"negative", []int{1, 2}, -1, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := &countingIterator[int]{it: ForSlice(tt.xs)}
+			got, err := readAll(Take[int](src, tt.n))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Take() got = %v, want %v", got, tt.want)
+			}
+			if src.calls != tt.wantCalls {
+				t.Errorf("Take() read source %d times, want %d", src.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestSkip(t *testing.T) {
+	tests := []struct {
+		name    string
+		it      Iterator[int]
+		n       int
+		want    []int
+		wantErr error
+	}{
+		{"empty", ForSlice[int](nil), 2, nil, nil},
+		{"fewer", ForSlice([]int{1}), 2, nil, nil},
+		{"exact", ForSlice([]int{1, 2}), 2, nil, nil},
+		{"more", ForSlice([]int{1, 2, 3, 4}), 2, []int{3, 4}, nil},
+		{"zero", ForSlice([]int{1, 2}), 0, []int{1, 2}, nil},
+		{"negative", ForSlice([]int{1, 2}), -1, []int{1, 2}, nil},
+		{"error while skipping", errIterator(errTest, 1), 2, nil, errTest},
+		{"error after skipping", errIterator(errTest, 1, 2, 3), 2, []int{3}, errTest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readAll(Skip(tt.it, tt.n))
+			if err != tt.wantErr {
+				t.Fatalf("Skip() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Skip() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkip_lazy(t *testing.T) {
+	src := &countingIterator[int]{it: ForSlice([]int{1, 2, 3})}
+	it := Take[int](Skip[int](src, 1), 1)
+	if src.calls != 0 {
+		t.Errorf("Skip() read source %d times before Next, want 0", src.calls)
+	}
+	got, err := readAll(it)
+	if err != nil || !reflect.DeepEqual(got, []int{2}) {
+		t.Errorf("Take(Skip()) got = %v, %v, want [2]", got, err)
+	}
+}
+
+func TestZip(t *testing.T) {
+	type pair = ZipPair[int, string]
+	tests := []struct {
+		name    string
+		it1     Iterator[int]
+		it2     Iterator[string]
+		want    []pair
+		wantErr error
+	}{
+		{"empty", ForSlice[int](nil), ForSlice([]string{"a"}), nil, nil},
+		{"same length", ForSlice([]int{1, 2}), ForSlice([]string{"a", "b"}), []pair{{1, "a"}, {2, "b"}}, nil},
+		{"first shorter", ForSlice([]int{1}), ForSlice([]string{"a", "b"}), []pair{{1, "a"}}, nil},
+		{"second shorter", ForSlice([]int{1, 2}), ForSlice([]string{"a"}), []pair{{1, "a"}}, nil},
+		{"error first", errIterator(errTest, 1), ForSlice([]string{"a", "b"}), []pair{{1, "a"}}, errTest},
+		{"error second", ForSlice([]int{1, 2}), errIterator(errTest, "a"), []pair{{1, "a"}}, errTest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readAll(Zip(tt.it1, tt.it2))
+			if err != tt.wantErr {
+				t.Fatalf("Zip() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Zip() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnumerate(t *testing.T) {
+	type indexed = IndexedValue[string]
+	tests := []struct {
+		name    string
+		it      Iterator[string]
+		want    []indexed
+		wantErr error
+	}{
+		{"empty", ForSlice[string](nil), nil, nil},
+		{"some", ForSlice([]string{"a", "b", "c"}), []indexed{{0, "a"}, {1, "b"}, {2, "c"}}, nil},
+		{"error", errIterator(errTest, "a"), []indexed{{0, "a"}}, errTest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readAll(Enumerate(tt.it))
+			if err != tt.wantErr {
+				t.Fatalf("Enumerate() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Enumerate() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	tests := []struct {
+		name    string
+		its     []Iterator[int]
+		want    []int
+		wantErr error
+	}{
+		{"none", nil, nil, nil},
+		{"single", []Iterator[int]{ForSlice([]int{1, 2})}, []int{1, 2}, nil},
+		{
+			"multiple",
+			[]Iterator[int]{ForSlice([]int{1, 2}), ForSlice[int](nil), ForSlice([]int{3})},
+			[]int{1, 2, 3},
+			nil,
+		},
+		{
+			"error",
+			[]Iterator[int]{ForSlice([]int{1}), errIterator(errTest, 2), ForSlice([]int{3})},
+			[]int{1, 2},
+			errTest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readAll(Chain(tt.its...))
+			if err != tt.wantErr {
+				t.Fatalf("Chain() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Chain() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name    string
+		its     []Iterator[int]
+		want    []int
+		wantErr error
+	}{
+		{"none", nil, nil, nil},
+		{"single", []Iterator[int]{ForSlice([]int{1, 2})}, []int{1, 2}, nil},
+		{
+			"multiple",
+			[]Iterator[int]{ForSlice([]int{1, 2}), ForSlice[int](nil), ForSlice([]int{3, 4, 5})},
+			[]int{1, 2, 3, 4, 5},
+			nil,
+		},
+		{
+			"error",
+			[]Iterator[int]{ForSlice([]int{1, 2, 3}), errIterator[int](errTest)},
+			nil,
+			errTest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it := Merge(tt.its...)
+			got, err := readAll(it)
+			if err != tt.wantErr {
+				t.Fatalf("Merge() error = %v, want %v", err, tt.wantErr)
+			}
+			wantAfter := tt.wantErr
+			if wantAfter == nil {
+				wantAfter = Done
+			}
+			if _, err := it.Next(); err != wantAfter {
+				t.Errorf("Next() after end got = %v, want %v", err, wantAfter)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			sort.Ints(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Merge() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerge_concurrent(t *testing.T) {
+	// the first iterator only yields after the second one's element is read
+	ch := make(chan int)
+	it := Merge(ForChannel(ch), ForSlice([]int{1}))
+	if x, err := it.Next(); err != nil || x != 1 {
+		t.Fatalf("Next() got = %v, %v, want 1", x, err)
+	}
+	go func() {
+		ch <- 2
+		close(ch)
+	}()
+	if x, err := it.Next(); err != nil || x != 2 {
+		t.Fatalf("Next() got = %v, %v, want 2", x, err)
+	}
+	if _, err := it.Next(); err != Done {
+		t.Errorf("Next() after end got = %v, want Done", err)
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	tests := []struct {
+		name    string
+		its     []Iterator[int]
+		want    []int
+		wantErr error
+	}{
+		{"none", nil, nil, nil},
+		{"single", []Iterator[int]{ForSlice([]int{1, 2})}, []int{1, 2}, nil},
+		{
+			"multiple",
+			[]Iterator[int]{ForSlice([]int{1, 4}), ForSlice[int](nil), ForSlice([]int{2, 5, 6, 7}), ForSlice([]int{3})},
+			[]int{1, 2, 3, 4, 5, 6, 7},
+			nil,
+		},
+		{
+			"error",
+			[]Iterator[int]{ForSlice([]int{1, 3, 5}), errIterator(errTest, 2)},
+			[]int{1, 2, 3},
+			errTest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it := RoundRobin(tt.its...)
+			got, err := readAll(it)
+			if err != tt.wantErr {
+				t.Fatalf("RoundRobin() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RoundRobin() got = %v, want %v", got, tt.want)
+			}
+			wantAfter := tt.wantErr
+			if wantAfter == nil {
+				wantAfter = Done
+			}
+			if _, err := it.Next(); err != wantAfter {
+				t.Errorf("Next() after end got = %v, want %v", err, wantAfter)
+			}
+		})
+	}
+}
+
+func TestBuffered(t *testing.T) {
+	tests := []struct {
+		name    string
+		it      Iterator[int]
+		size    int
+		want    []int
+		wantErr error
+	}{
+		{"empty", ForSlice[int](nil), 1, nil, nil},
+		{"size 1", ForSlice([]int{1, 2, 3}), 1, []int{1, 2, 3}, nil},
+		{"larger than source", ForSlice([]int{1, 2, 3}), 10, []int{1, 2, 3}, nil},
+		{"error", errIterator(errTest, 1, 2), 2, []int{1, 2}, errTest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it := Buffered(tt.it, tt.size)
+			got, err := readAll[int](it)
+			if err != tt.wantErr {
+				t.Fatalf("Buffered() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Buffered() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuffered_readAhead(t *testing.T) {
+	calls := make(chan int)
+	i := 0
+	src := iteratorFunc[int](func() (int, error) {
+		i += 1
+		calls <- i
+		return i, nil
+	})
+	it := Buffered[int](src, 3).(*bufferedIterator[int])
+	select {
+	case <-calls:
+		t.Fatalf("read before first Next")
+	case <-time.After(10 * time.Millisecond):
+	}
+	first := make(chan error)
+	go func() {
+		x, err := it.Next()
+		if err == nil && x != 1 {
+			err = fmt.Errorf("got %v, want 1", x)
+		}
+		first <- err
+	}()
+	// one for Next, two in the buffer and one waiting to be buffered
+	for want := 1; want <= 4; want++ {
+		if got := <-calls; got != want {
+			t.Fatalf("source call got = %v, want %v", got, want)
+		}
+	}
+	select {
+	case <-calls:
+		t.Fatalf("read beyond buffer size")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if err := <-first; err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+
+	if err := it.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := it.Next(); err != Done {
+		t.Errorf("Next() after Close got = %v, want Done", err)
+	}
+	// unblock the pending call, after which the goroutine should end
+	go func() {
+		for range calls {
+		}
+	}()
+	select {
+	case <-waitClosed(it.ch):
+	case <-time.After(time.Second):
+		t.Errorf("goroutine did not stop")
+	}
+	close(calls)
+}
+
+// waitClosed returns a channel that is closed once ch is drained and closed.
+func waitClosed[T any](ch <-chan T) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(done)
+	}()
+	return done
+}
+
+func TestBuffered_panic(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Buffered() did not panic")
+		}
+	}()
+	Buffered(ForSlice([]int{1}), 0)
+}
+
+func TestBuffered_closeUnread(t *testing.T) {
+	calls := 0
+	src := iteratorFunc[int](func() (int, error) {
+		calls += 1
+		return calls, nil
+	})
+	it := Buffered[int](src, 2)
+	c, ok := it.(io.Closer)
+	if !ok {
+		t.Fatalf("Buffered() does not implement io.Closer")
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := it.Next(); err != Done || calls != 0 {
+		t.Errorf("Next() after Close got = %v after %d calls, want Done after 0", err, calls)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		it      Iterator[int]
+		want    []int
+		wantErr error
+	}{
+		{"empty", ForSlice[int](nil), nil, nil},
+		{"some", ForSlice([]int{1, 2, 3}), []int{1, 2, 3}, nil},
+		{"error", errIterator(errTest, 1), []int{1}, errTest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readAll(WithTimeout(tt.it, time.Second))
+			if err != tt.wantErr {
+				t.Fatalf("WithTimeout() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WithTimeout() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithTimeout_retry(t *testing.T) {
+	ch := make(chan int)
+	it := WithTimeout(ForChannel(ch), 10*time.Millisecond)
+	if _, err := it.Next(); err != Timeout {
+		t.Fatalf("Next() got = %v, want Timeout", err)
+	}
+	if _, err := it.Next(); err != Timeout {
+		t.Fatalf("Next() got = %v, want Timeout", err)
+	}
+	go func() {
+		ch <- 1
+		ch <- 2
+		close(ch)
+	}()
+	var got []int
+	for attempts := 0; attempts < 1000; attempts++ {
+		x, err := it.Next()
+		if err == Done {
+			break
+		}
+		if err == Timeout {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, x)
+	}
+	if want := []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Next() after timeout got = %v, want %v", got, want)
+	}
+}
+
+func TestTee(t *testing.T) {
+	its := Tee(ForSlice([]int{1, 2, 3}), 3)
+	if len(its) != 3 {
+		t.Fatalf("Tee() got %d iterators, want 3", len(its))
+	}
+	// read at different paces from a single goroutine
+	if x, err := its[0].Next(); x != 1 || err != nil {
+		t.Errorf("Next() got = %v, %v, want 1", x, err)
+	}
+	for i := len(its) - 1; i >= 0; i -= 1 {
+		got, err := readAll(its[i])
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := []int{1, 2, 3}
+		if i == 0 {
+			want = want[1:]
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("iterator %d got = %v, want %v", i, got, want)
+		}
+	}
+	if its := Tee(ForSlice([]int{1}), 0); its != nil {
+		t.Errorf("Tee() with n = 0 got = %v, want nil", its)
+	}
+}
+
+func TestTee_error(t *testing.T) {
+	its := Tee(errIterator(errTest, 1), 2)
+	for i, it := range its {
+		got, err := readAll(it)
+		if err != errTest || !reflect.DeepEqual(got, []int{1}) {
+			t.Errorf("iterator %d got = %v, %v, want [1], %v", i, got, err, errTest)
+		}
+	}
+}
+
+func TestTeeWithBuffer(t *testing.T) {
+	xs := make([]int, 100)
+	for i := range xs {
+		xs[i] = i
+	}
+	its := TeeWithBuffer(ForSlice(xs), 3, 2)
+	results := make([][]int, len(its))
+	errs := make([]error, len(its))
+	wg := sync.WaitGroup{}
+	for i := range its {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = readAll(its[i])
+		}(i)
+	}
+	wg.Wait()
+	for i := range its {
+		if errs[i] != nil {
+			t.Errorf("iterator %d unexpected error: %v", i, errs[i])
+		}
+		if !reflect.DeepEqual(results[i], xs) {
+			t.Errorf("iterator %d got = %v, want %v", i, results[i], xs)
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		it      Iterator[int]
+		size    int
+		want    [][]int
+		wantErr error
+	}{
+		{"empty", ForSlice[int](nil), 2, nil, nil},
+		{"exact", ForSlice([]int{1, 2, 3, 4}), 2, [][]int{{1, 2}, {3, 4}}, nil},
+		{"remainder", ForSlice([]int{1, 2, 3}), 2, [][]int{{1, 2}, {3}}, nil},
+		{"size one", ForSlice([]int{1, 2}), 1, [][]int{{1}, {2}}, nil},
+		{"error", errIterator(errTest, 1, 2, 3), 2, [][]int{{1, 2}, {3}}, errTest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it := Batch(tt.it, tt.size)
+			got, err := readAll(it)
+			if err != tt.wantErr {
+				t.Fatalf("Batch() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Batch() got = %v, want %v", got, tt.want)
+			}
+			if _, err := it.Next(); err == nil {
+				t.Errorf("Next() after end got nil error")
+			}
+		})
+	}
+}
+
+func TestBatch_panic(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Batch() with size 0 did not panic")
+		}
+	}()
+	Batch(ForSlice([]int{1}), 0)
+}
+
+func TestWindow(t *testing.T) {
+	tests := []struct {
+		name    string
+		it      Iterator[int]
+		size    int
+		want    [][]int
+		wantErr error
+	}{
+		{"empty", ForSlice[int](nil), 2, nil, Done},
+		{"too short", ForSlice([]int{1}), 2, nil, Done},
+		{"pairs", ForSlice([]int{1, 2, 3, 4}), 2, [][]int{{1, 2}, {2, 3}, {3, 4}}, Done},
+		{"exact", ForSlice([]int{1, 2, 3}), 3, [][]int{{1, 2, 3}}, Done},
+		{"size one", ForSlice([]int{1, 2}), 1, [][]int{{1}, {2}}, Done},
+		{"error", errIterator(errTest, 1, 2, 3), 2, [][]int{{1, 2}, {2, 3}}, errTest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it := Window(tt.it, tt.size)
+			got, err := readAll(it)
+			if err != nil && err != tt.wantErr {
+				t.Fatalf("Window() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Window() got = %v, want %v", got, tt.want)
+			}
+			if _, err := it.Next(); err != tt.wantErr {
+				t.Errorf("Next() after end got = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWindow_copies(t *testing.T) {
+	it := Window(ForSlice([]int{1, 2, 3}), 2)
+	w1, _ := it.Next()
+	w1[1] = 42
+	if w2, err := it.Next(); err != nil || !reflect.DeepEqual(w2, []int{2, 3}) {
+		t.Errorf("Next() got = %v, %v, want [2 3]", w2, err)
+	}
+}
+
+func TestWindow_panic(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Window() with size 0 did not panic")
+		}
+	}()
+	Window(ForSlice([]int{1}), 0)
+}
+
+func TestDistinct(t *testing.T) {
+	tests := []struct {
+		name    string
+		it      Iterator[int]
+		want    []int
+		wantErr error
+	}{
+		{"empty", ForSlice[int](nil), nil, nil},
+		{"no duplicates", ForSlice([]int{1, 2, 3}), []int{1, 2, 3}, nil},
+		{"duplicates", ForSlice([]int{1, 2, 1, 3, 2, 1}), []int{1, 2, 3}, nil},
+		{"error", errIterator(errTest, 1, 1, 2), []int{1, 2}, errTest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readAll(Distinct(tt.it))
+			if err != tt.wantErr {
+				t.Fatalf("Distinct() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Distinct() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDistinctBy(t *testing.T) {
+	xs := [][]string{{"a", "1"}, {"b", "2"}, {"a", "3"}}
+	got, err := readAll(DistinctBy(ForSlice(xs), func(x []string) string { return x[0] }))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := [][]string{{"a", "1"}, {"b", "2"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DistinctBy() got = %v, want %v", got, want)
+	}
+}
+
+func TestSort(t *testing.T) {
+	type pair struct {
+		k string
+		v int
+	}
+	less := func(a, b pair) bool { return a.k < b.k }
+	tests := []struct {
+		name    string
+		it      Iterator[pair]
+		want    []pair
+		wantErr error
+	}{
+		{"empty", ForSlice[pair](nil), nil, nil},
+		{"sorted", ForSlice([]pair{{"a", 1}, {"b", 2}}), []pair{{"a", 1}, {"b", 2}}, nil},
+		{"unsorted", ForSlice([]pair{{"c", 1}, {"a", 2}, {"b", 3}}), []pair{{"a", 2}, {"b", 3}, {"c", 1}}, nil},
+		{"stable", ForSlice([]pair{{"b", 1}, {"a", 2}, {"b", 3}, {"a", 4}}), []pair{{"a", 2}, {"a", 4}, {"b", 1}, {"b", 3}}, nil},
+		{"error", errIterator(errTest, pair{"b", 1}, pair{"a", 2}), nil, errTest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readAll(Sort(tt.it, less))
+			if err != tt.wantErr {
+				t.Fatalf("Sort() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Sort() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToSlice(t *testing.T) {
+	tests := []struct {
+		name    string
+		it      Iterator[int]
+		want    []int
+		wantErr error
+	}{
+		{"empty", ForSlice[int](nil), []int{}, nil},
+		{"some", ForSlice([]int{1, 2, 3}), []int{1, 2, 3}, nil},
+		{"error", errIterator(errTest, 1, 2), []int{1, 2}, errTest},
+		{"error, empty", errIterator[int](errTest), []int{}, errTest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToSlice(tt.it)
+			if err != tt.wantErr {
+				t.Fatalf("ToSlice() error = %v, want %v", err, tt.wantErr)
+			}
+			if got == nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ToSlice() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		it      Iterator[string]
+		want    map[byte]string
+		wantErr error
+	}{
+		{"empty", ForSlice[string](nil), map[byte]string{}, nil},
+		{"some", ForSlice([]string{"ab", "bc"}), map[byte]string{'a': "ab", 'b': "bc"}, nil},
+		{"last wins", ForSlice([]string{"ab", "ac"}), map[byte]string{'a': "ac"}, nil},
+		{"error", errIterator(errTest, "ab"), map[byte]string{'a': "ab"}, errTest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToMap(tt.it, func(s string) byte { return s[0] })
+			if err != tt.wantErr {
+				t.Fatalf("ToMap() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ToMap() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectMap(t *testing.T) {
+	type entry = Entry[string, int]
+	tests := []struct {
+		name    string
+		it      Iterator[entry]
+		want    map[string]int
+		wantErr error
+	}{
+		{"empty", ForSlice[entry](nil), map[string]int{}, nil},
+		{"some", ForSlice([]entry{{"a", 1}, {"b", 2}}), map[string]int{"a": 1, "b": 2}, nil},
+		{"last wins", ForSlice([]entry{{"a", 1}, {"a", 2}}), map[string]int{"a": 2}, nil},
+		{"error", errIterator(errTest, entry{"a", 1}), map[string]int{"a": 1}, errTest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CollectMap(tt.it)
+			if err != tt.wantErr {
+				t.Fatalf("CollectMap() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CollectMap() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		name    string
+		it      Iterator[int]
+		want    int
+		wantErr error
+	}{
+		{"empty", ForSlice[int](nil), 0, nil},
+		{"some", ForSlice([]int{1, 2, 3}), 3, nil},
+		{"error", errIterator(errTest, 1, 2), 2, errTest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Count(tt.it)
+			if err != tt.wantErr {
+				t.Fatalf("Count() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Count() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFirst(t *testing.T) {
+	tests := []struct {
+		name      string
+		it        Iterator[int]
+		want      int
+		wantFound bool
+		wantErr   error
+		wantNext  int
+	}{
+		{"empty", ForSlice[int](nil), 0, false, nil, 0},
+		{"some", ForSlice([]int{1, 2, 3}), 1, true, nil, 2},
+		{"error", errIterator[int](errTest), 0, false, errTest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found, err := First(tt.it)
+			if err != tt.wantErr {
+				t.Fatalf("First() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want || found != tt.wantFound {
+				t.Errorf("First() got = %v, %v, want %v, %v", got, found, tt.want, tt.wantFound)
+			}
+			if tt.wantNext != 0 {
+				if next, err := tt.it.Next(); err != nil || next != tt.wantNext {
+					t.Errorf("Next() after First() got = %v, %v, want %v", next, err, tt.wantNext)
+				}
+			}
+		})
+	}
+}
+
+func TestLast(t *testing.T) {
+	tests := []struct {
+		name      string
+		it        Iterator[int]
+		want      int
+		wantFound bool
+		wantErr   error
+	}{
+		{"empty", ForSlice[int](nil), 0, false, nil},
+		{"one", ForSlice([]int{1}), 1, true, nil},
+		{"some", ForSlice([]int{1, 2, 3}), 3, true, nil},
+		{"error", errIterator(errTest, 1, 2), 0, false, errTest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found, err := Last(tt.it)
+			if err != tt.wantErr {
+				t.Fatalf("Last() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want || found != tt.wantFound {
+				t.Errorf("Last() got = %v, %v, want %v, %v", got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestAny(t *testing.T) {
+	even := func(i int) bool { return i%2 == 0 }
+	tests := []struct {
+		name      string
+		it        Iterator[int]
+		want      bool
+		wantErr   error
+		wantCalls int
+	}{
+		{"empty", ForSlice[int](nil), false, nil, 1},
+		{"none", ForSlice([]int{1, 3}), false, nil, 3},
+		{"first", ForSlice([]int{2, 3}), true, nil, 1},
+		{"later", ForSlice([]int{1, 2, 3}), true, nil, 2},
+		{"error", errIterator(errTest, 1), false, errTest, 2},
+		{"match before error", errIterator(errTest, 2), true, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it := &countingIterator[int]{it: tt.it}
+			got, err := Any[int](it, even)
+			if err != tt.wantErr {
+				t.Fatalf("Any() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Any() got = %v, want %v", got, tt.want)
+			}
+			if it.calls != tt.wantCalls {
+				t.Errorf("Any() called Next %d times, want %d", it.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestAll(t *testing.T) {
+	even := func(i int) bool { return i%2 == 0 }
+	tests := []struct {
+		name      string
+		it        Iterator[int]
+		want      bool
+		wantErr   error
+		wantCalls int
+	}{
+		{"empty", ForSlice[int](nil), true, nil, 1},
+		{"all", ForSlice([]int{2, 4}), true, nil, 3},
+		{"first", ForSlice([]int{1, 2}), false, nil, 1},
+		{"later", ForSlice([]int{2, 3, 4}), false, nil, 2},
+		{"error", errIterator(errTest, 2), false, errTest, 2},
+		{"mismatch before error", errIterator(errTest, 1), false, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it := &countingIterator[int]{it: tt.it}
+			got, err := All[int](it, even)
+			if err != tt.wantErr {
+				t.Fatalf("All() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("All() got = %v, want %v", got, tt.want)
+			}
+			if it.calls != tt.wantCalls {
+				t.Errorf("All() called Next %d times, want %d", it.calls, tt.wantCalls)
+			}
+		})
+	}
+}
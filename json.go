@@ -0,0 +1,68 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// jsonCondition is the JSON representation of a condition. The separator
+// links the condition to the next one; it is empty for the last condition.
+type jsonCondition struct {
+	Key      string   `json:"key"`
+	KeyParts []string `json:"keyParts"`
+	Op       string   `json:"op"`
+	Value    string   `json:"value"`
+	Quoted   bool     `json:"quoted,omitempty"`
+	Sep      string   `json:"sep,omitempty"`
+}
+
+// MarshalJSON encodes the filter as an array of conditions in order of
+// appearance.
+func (f filter) MarshalJSON() ([]byte, error) {
+	jcs := make([]jsonCondition, 0, f.Size())
+	for c := f.first; c != nil; {
+		next, sep := c.next()
+		jcs = append(jcs, jsonCondition{c.key, c.keyParts, c.op, c.stringValue, c.quoted, sep})
+		c = next
+	}
+	return json.Marshal(jcs)
+}
+
+// FilterFromJSON decodes a Filter encoded by its MarshalJSON method. Keys
+// must follow the naming rules of the filter grammar and, like with the
+// parser, only the operators '=' and '!=' are accepted unless others are
+// registered with OptionOperators.
+func FilterFromJSON(data []byte, options ...Option) (Filter, error) {
+	var jcs []jsonCondition
+	if err := json.Unmarshal(data, &jcs); err != nil {
+		return nil, fmt.Errorf("invalid filter JSON: %v", err)
+	}
+	p := NewParser(options...).(*parser)
+	cs := make([]condition, len(jcs))
+	seps := make([]string, 0, len(jcs))
+	for i, jc := range jcs {
+		if jc.KeyParts != nil && strings.Join(jc.KeyParts, string(nameSeparator)) != jc.Key {
+			return nil, fmt.Errorf("condition %d: key parts %v do not match key %q", i, jc.KeyParts, jc.Key)
+		}
+		c, err := p.decodeCondition(jc.Key, jc.Op, jc.Value, jc.Quoted)
+		if err != nil {
+			return nil, fmt.Errorf("condition %d: %v", i, err)
+		}
+		last := i == len(jcs)-1
+		switch {
+		case last && jc.Sep != "":
+			return nil, fmt.Errorf("condition %d: unexpected separator %q after last condition", i, jc.Sep)
+		case !last && jc.Sep != separatorAnd && jc.Sep != separatorOr:
+			return nil, fmt.Errorf("condition %d: invalid separator %q, expected %s or %s", i, jc.Sep, separatorAnd, separatorOr)
+		case !last:
+			seps = append(seps, jc.Sep)
+		}
+		cs[i] = c
+	}
+	return newFilter(cs, seps), nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFilter_MarshalJSON(t *testing.T) {
+	f, _ := NewParser().Parse(`foo.bar=1 AND bla="x y" OR foo.bar!=2`)
+	got, err := json.Marshal(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `[` +
+		`{"key":"foo.bar","keyParts":["foo","bar"],"op":"=","value":"1","sep":"AND"},` +
+		`{"key":"bla","keyParts":["bla"],"op":"=","value":"x y","quoted":true,"sep":"OR"},` +
+		`{"key":"foo.bar","keyParts":["foo","bar"],"op":"!=","value":"2"}` +
+		`]`
+	if string(got) != want {
+		t.Errorf("MarshalJSON() = %s, want %s", got, want)
+	}
+}
+
+func TestFilterFromJSON_roundTrip(t *testing.T) {
+	tests := []string{
+		"",
+		"foo=bar",
+		"foo.bar=1 AND bla=vla OR foo.bar=2",
+		`foo="x y" OR bla="" AND moo=boo`,
+	}
+	for _, query := range tests {
+		t.Run(query, func(t *testing.T) {
+			f, _ := NewParser().Parse(query)
+			data, err := json.Marshal(f)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := FilterFromJSON(data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.String() != f.String() {
+				t.Errorf("String() = %v, want %v", got, f)
+			}
+			if !got.Equal(f) {
+				t.Errorf("Equal() = false for %v and %v", got, f)
+			}
+			if !reflect.DeepEqual(got.Keys(), f.Keys()) {
+				t.Errorf("Keys() = %v, want %v", got.Keys(), f.Keys())
+			}
+			for _, k := range f.Keys() {
+				a, _ := got.GetFirst(k)
+				b, _ := f.GetFirst(k)
+				if !conditionsEqual(a, b) {
+					t.Errorf("GetFirst(%s) = %v, want %v", k, a, b)
+				}
+				a, _ = got.GetLast(k)
+				b, _ = f.GetLast(k)
+				if !conditionsEqual(a, b) {
+					t.Errorf("GetLast(%s) = %v, want %v", k, a, b)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterFromJSON_operators(t *testing.T) {
+	f, _ := NewParser(OptionOperators("<")).Parse("a<1")
+	data, err := json.Marshal(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := FilterFromJSON(data); err == nil {
+		t.Errorf("expected error for unregistered operator")
+	}
+	got, err := FilterFromJSON(data, OptionOperators("<"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got.Equal(f) {
+		t.Errorf("Equal() = false for %v and %v", got, f)
+	}
+}
+
+func TestFilterFromJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{"empty", `[]`, "", false},
+		{"null", `null`, "", false},
+		{"without key parts", `[{"key":"a.b","op":"=","value":"1"}]`, "a.b=1", false},
+		{"! invalid JSON", `[{"key":`, "", true},
+		{"! not an array", `{"key":"a"}`, "", true},
+		{"! unknown separator", `[{"key":"a","op":"=","value":"1","sep":"XOR"},{"key":"b","op":"=","value":"2"}]`, "", true},
+		{"! missing separator", `[{"key":"a","op":"=","value":"1"},{"key":"b","op":"=","value":"2"}]`, "", true},
+		{"! trailing separator", `[{"key":"a","op":"=","value":"1","sep":"AND"}]`, "", true},
+		{"! missing key", `[{"op":"=","value":"1"}]`, "", true},
+		{"! missing operator", `[{"key":"a","value":"1"}]`, "", true},
+		{"! key parts mismatch", `[{"key":"a.b","keyParts":["a"],"op":"=","value":"1"}]`, "", true},
+		{"! invalid key", `[{"key":"a b)(","op":"=","value":"1"}]`, "", true},
+		{"! invalid key parts", `[{"key":"a..b","keyParts":["a","","b"],"op":"=","value":"1"}]`, "", true},
+		{"! unknown operator", `[{"key":"a","op":"~~","value":"1"}]`, "", true},
+		{"! unregistered operator", `[{"key":"a","op":"<","value":"1"}]`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FilterFromJSON([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FilterFromJSON() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("FilterFromJSON() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// labelPattern matches valid label names and (non-empty) label values.
+var labelPattern = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+
+// labelPrefixPattern matches valid label key prefixes: DNS subdomains.
+var labelPrefixPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+// labelSetPattern matches set-based requirements.
+var labelSetPattern = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+
+// A labelRequirement is a single requirement of a label selector. Its op is
+// one of "=", "!=", "in", "notin", "exists" and "!".
+type labelRequirement struct {
+	key    string
+	op     string
+	values []string
+}
+
+func (r labelRequirement) String() string {
+	switch r.op {
+	case "exists":
+		return r.key
+	case "!":
+		return "!" + r.key
+	case "in", "notin":
+		return r.key + " " + r.op + " (" + strings.Join(r.values, ",") + ")"
+	}
+	return r.key + r.op + r.values[0]
+}
+
+// FromLabelSelector converts a Kubernetes label selector, like
+// "env=prod,tier in (web,api)", into a Filter. Equality ('=' or '==') and
+// inequality map directly. Set-based requirements map to conditions on every
+// value: those for 'in' linked with OR and those for 'notin' with AND. An
+// existence requirement maps to the has operator with the value '*' (like
+// "env:*") and its negation to "env!=*". An empty selector results in an
+// empty Filter. Invalid label names or values, including empty value lists,
+// result in an error.
+//
+// Kubernetes selectors match objects without the label for inequality and
+// 'notin'. To evaluate the resulting filters the same way, use
+// MatchOptionMissingField with MissingFieldMatchNotEqual.
+//
+// Label keys, like "app.kubernetes.io/name", are used as a whole: the
+// resulting conditions have a single key part, so dots in the key do not
+// refer to nested fields.
+func FromLabelSelector(s string) (Filter, error) {
+	ops := NewParser(OptionOperators(":")).(*parser).ops
+	var cs []condition
+	var seps []string
+	add := func(sep, k, op, v string) {
+		if len(cs) > 0 {
+			seps = append(seps, sep)
+		}
+		quoted := needsQuotes(v) || filter{ops: ops}.extendsOperator(op, v)
+		cs = append(cs, condition{k, []string{k}, op, v, quoted, nil, nil, nil})
+	}
+	rs, err := splitLabelSelector(s)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rs {
+		r = strings.TrimSpace(r)
+		if m := labelSetPattern.FindStringSubmatch(r); m != nil {
+			vs := strings.Split(m[3], ",")
+			for i, v := range vs {
+				vs[i] = strings.TrimSpace(v)
+				if vs[i] == "" {
+					return nil, fmt.Errorf("invalid label selector %q: empty value in %q", s, r)
+				}
+			}
+			if err := checkLabel(m[1], vs...); err != nil {
+				return nil, fmt.Errorf("invalid label selector %q: %v", s, err)
+			}
+			for i, v := range vs {
+				switch {
+				case m[2] == "notin":
+					add(separatorAnd, m[1], "!=", v)
+				case i == 0:
+					add(separatorAnd, m[1], "=", v)
+				default:
+					add(separatorOr, m[1], "=", v)
+				}
+			}
+			continue
+		}
+		var k, op, v string
+		var vs []string
+		if i := strings.Index(r, "!="); i >= 0 {
+			k, op, v = r[:i], "!=", strings.TrimSpace(r[i+2:])
+			vs = []string{v}
+		} else if i = strings.Index(r, "="); i >= 0 {
+			k, op, v = r[:i], "=", strings.TrimSpace(strings.TrimPrefix(r[i+1:], "="))
+			vs = []string{v}
+		} else if strings.HasPrefix(r, "!") {
+			k, op, v = r[1:], "!=", "*"
+		} else {
+			k, op, v = r, ":", "*"
+		}
+		k = strings.TrimSpace(k)
+		if err := checkLabel(k, vs...); err != nil {
+			return nil, fmt.Errorf("invalid label selector %q: %v", s, err)
+		}
+		add(separatorAnd, k, op, v)
+	}
+	f := newFilter(cs, seps)
+	f.ops = ops
+	return f, nil
+}
+
+// checkLabel returns an error if the key is not a valid label name or one of
+// the values is not a valid label value. Empty values are valid.
+func checkLabel(key string, values ...string) error {
+	if !isLabelKey(key) {
+		return fmt.Errorf("%q is not a valid label name", key)
+	}
+	for _, v := range values {
+		if v != "" && !labelPattern.MatchString(v) {
+			return fmt.Errorf("%s: %q is not a valid label value", key, v)
+		}
+	}
+	return nil
+}
+
+// isLabelKey reports whether the key is a valid label key: a name of at most
+// 63 characters, optionally preceded by a DNS subdomain prefix of at most 253
+// characters and a '/'.
+func isLabelKey(key string) bool {
+	name := key
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		prefix := key[:i]
+		if len(prefix) > 253 || !labelPrefixPattern.MatchString(prefix) {
+			return false
+		}
+		name = key[i+1:]
+	}
+	return len(name) <= 63 && labelPattern.MatchString(name)
+}
+
+// splitLabelSelector splits the selector into its requirements, ignoring the
+// commas within value lists.
+func splitLabelSelector(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var rs []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth += 1
+		case ')':
+			depth -= 1
+		case ',':
+			if depth == 0 {
+				rs = append(rs, s[start:i])
+				start = i + 1
+			}
+		}
+		if depth < 0 || depth > 1 {
+			return nil, fmt.Errorf("invalid label selector %q: unbalanced parentheses", s)
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("invalid label selector %q: unbalanced parentheses", s)
+	}
+	return append(rs, s[start:]), nil
+}
+
+// ToLabelSelector converts the filter into a Kubernetes label selector. It
+// is the inverse of FromLabelSelector: conditions with '=' or ':' become
+// equality requirements and those with '!=' inequality requirements, with
+// the value '*' meaning (non-)existence. An OR group of equality conditions
+// on the same key becomes an 'in' requirement and consecutive inequality
+// conditions on the same key become a 'notin' requirement. An error is
+// returned for filters that cannot be expressed as a label selector: those
+// with nested (dotted) keys, other operators, other OR groups or values that are not
+// valid label values.
+func ToLabelSelector(f Filter) (string, error) {
+	var rs []labelRequirement
+	for _, g := range orGroups(f) {
+		r, err := toLabelRequirement(g)
+		if err != nil {
+			return "", err
+		}
+		if n := len(rs); n > 0 && r.op == "!=" && rs[n-1].key == r.key &&
+			(rs[n-1].op == "!=" || rs[n-1].op == "notin") {
+			rs[n-1].op = "notin"
+			rs[n-1].values = append(rs[n-1].values, r.values...)
+			continue
+		}
+		rs = append(rs, r)
+	}
+	ss := make([]string, len(rs))
+	for i, r := range rs {
+		ss[i] = r.String()
+	}
+	return strings.Join(ss, ","), nil
+}
+
+// toLabelRequirement converts an OR group into a label requirement.
+func toLabelRequirement(g []Condition) (labelRequirement, error) {
+	c := g[0]
+	if len(c.KeyParts()) > 1 || !isLabelKey(c.Key()) {
+		return labelRequirement{}, fmt.Errorf("%s: not a valid label name", c.Key())
+	}
+	if len(g) == 1 {
+		return toLabelCondition(c)
+	}
+	r := labelRequirement{c.Key(), "in", nil}
+	for _, c := range g {
+		x, err := toLabelCondition(c)
+		if err != nil {
+			return labelRequirement{}, err
+		}
+		if x.key != r.key || x.op != "=" {
+			return labelRequirement{}, fmt.Errorf("%s: label selectors only support OR between equality conditions on the same key", c.Key())
+		}
+		r.values = append(r.values, x.values...)
+	}
+	return r, nil
+}
+
+// toLabelCondition converts a single condition into a label requirement.
+func toLabelCondition(c Condition) (labelRequirement, error) {
+	k, v := c.Key(), c.StringValue()
+	star := v == "*" && !c.IsQuoted()
+	switch {
+	case c.Op() != "=" && c.Op() != "!=" && c.Op() != ":":
+		return labelRequirement{}, fmt.Errorf("%s: label selectors do not support operator %s", k, c.Op())
+	case star && c.Op() == "!=":
+		return labelRequirement{k, "!", nil}, nil
+	case star:
+		return labelRequirement{k, "exists", nil}, nil
+	case v != "" && !labelPattern.MatchString(v):
+		return labelRequirement{}, fmt.Errorf("%s: %q is not a valid label value", k, v)
+	case c.Op() == "!=":
+		return labelRequirement{k, "!=", []string{v}}, nil
+	}
+	return labelRequirement{k, "=", []string{v}}, nil
+}
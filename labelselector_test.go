@@ -0,0 +1,126 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import "testing"
+
+func TestFromLabelSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+		want     string
+		wantErr  bool
+	}{
+		{"empty", "", "", false},
+		{"equality", "env=prod", "env=prod", false},
+		{"double equals", "env==prod", "env=prod", false},
+		{"inequality", "env!=prod", "env!=prod", false},
+		{"in", "tier in (web,api)", "tier=web OR tier=api", false},
+		{"notin", "tier notin (web, api)", "tier!=web AND tier!=api", false},
+		{"exists", "env", "env:*", false},
+		{"not exists", "!env", "env!=*", false},
+		{
+			"combined",
+			"env=prod, tier in (web,api),!canary",
+			"env=prod AND tier=web OR tier=api AND canary!=*",
+			false,
+		},
+		{"empty value", "env=", "env=", false},
+		{"prefixed key", "app.kubernetes.io/name=foo", "app.kubernetes.io/name=foo", false},
+		{"dashed key", "app-name in (web,api)", "app-name=web OR app-name=api", false},
+		{"! empty prefix", "/name=foo", "", true},
+		{"! invalid prefix", "App.io/name=foo", "", true},
+		{"! double prefix", "a.io/b/name=foo", "", true},
+		{"! unbalanced", "tier in (web,api", "", true},
+		{"! empty requirement", "env=prod,,tier=web", "", true},
+		{"! empty set", "tier in ()", "", true},
+		{"! empty set value", "tier in (web,)", "", true},
+		{"! invalid key", "en v=prod", "", true},
+		{"! invalid value", "env=pro d", "", true},
+		{"! invalid value with star", "env=*", "", true},
+		{"! invalid set value", "tier notin (web,a*)", "", true},
+		{"! invalid existence key", "!en*", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := FromLabelSelector(tt.selector)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FromLabelSelector() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := f.String(); got != tt.want {
+				t.Errorf("FromLabelSelector() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToLabelSelector(t *testing.T) {
+	p := NewParser(OptionOperators("<", ">", "<=", ">=", ":"))
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{"empty", "", "", false},
+		{"equality", "env=prod AND tier:web", "env=prod,tier=web", false},
+		{"inequality", "env!=prod", "env!=prod", false},
+		{"in", "tier=web OR tier:api", "tier in (web,api)", false},
+		{"notin", "tier!=web AND tier!=api AND tier!=db", "tier notin (web,api,db)", false},
+		{"separate inequalities", "tier!=web AND env!=prod AND tier!=api", "tier!=web,env!=prod,tier!=api", false},
+		{"exists", "env:* AND canary!=*", "env,!canary", false},
+		{"! quoted star", `env="*"`, "", true},
+		{"! dotted key", "owner.name=joe", "", true},
+		{"! ordering operator", "replicas>1", "", true},
+		{"! or on different keys", "env=prod OR tier=web", "", true},
+		{"! or with inequality", "env=prod OR env!=dev", "", true},
+		{"! invalid value", `env="pro d"`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got, err := ToLabelSelector(f)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToLabelSelector() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ToLabelSelector() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLabelSelector_roundTrip(t *testing.T) {
+	selectors := []string{
+		"",
+		"env=prod",
+		"env!=prod",
+		"env=",
+		"tier in (web,api)",
+		"tier notin (web,api)",
+		"env,!canary",
+		"env=prod,tier in (web,api),track notin (canary,beta),!legacy",
+		"app.kubernetes.io/name=web",
+		"app.kubernetes.io/name in (web,api),!example.com/legacy",
+		"app.kubernetes.io/part-of notin (shop,blog)",
+	}
+	for _, s := range selectors {
+		f, err := FromLabelSelector(s)
+		if err != nil {
+			t.Errorf("FromLabelSelector(%q) unexpected error: %v", s, err)
+			continue
+		}
+		got, err := ToLabelSelector(f)
+		if err != nil || got != s {
+			t.Errorf("ToLabelSelector(FromLabelSelector(%q)) got = %q, %v", s, got, err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ToLDAP translates the filter into an LDAP search filter (RFC 4515). Several
+// OR groups are combined with '&' and several conditions in an OR group with
+// '|'. Conditions with '!=' are negated with '!'. Like Matches, '=', '!=' and
+// ':' treat values with '*' wildcards as patterns, which become presence
+// filters (like "(attr=*)") or substring filters; the '?' wildcard has no
+// LDAP equivalent and results in an error. Since LDAP filters only support
+// '>=' and '<=', a strict comparison is rewritten, for instance a<1 to
+// "(&(a<=1)(!(a=1)))". Special characters in values are escaped. Keys must be
+// valid attribute descriptions (RFC 4512). Other keys or operators result in
+// an error, as does an empty filter.
+func ToLDAP(f Filter) (string, error) {
+	var ands []string
+	for _, g := range orGroups(f) {
+		var ors []string
+		for _, c := range g {
+			s, err := ldapItem(c)
+			if err != nil {
+				return "", err
+			}
+			ors = append(ors, s)
+		}
+		ands = append(ands, ldapJoin('|', ors))
+	}
+	if len(ands) == 0 {
+		return "", fmt.Errorf("cannot express an empty filter in LDAP")
+	}
+	return ldapJoin('&', ands), nil
+}
+
+// ldapJoin combines the filters with the operator, unless there is only one.
+func ldapJoin(op byte, fs []string) string {
+	if len(fs) == 1 {
+		return fs[0]
+	}
+	return "(" + string(op) + strings.Join(fs, "") + ")"
+}
+
+// ldapAttribute matches an attribute description (RFC 4512): a descriptor or
+// numeric OID, optionally followed by options.
+var ldapAttribute = regexp.MustCompile(`^([A-Za-z][-A-Za-z0-9]*|[0-9]+(\.[0-9]+)*)(;[-A-Za-z0-9]+)*$`)
+
+// ldapItem translates a single condition.
+func ldapItem(c Condition) (string, error) {
+	k := c.Key()
+	if !ldapAttribute.MatchString(k) {
+		return "", fmt.Errorf("%q is not a valid LDAP attribute description", k)
+	}
+	switch c.Op() {
+	case "=", ":":
+		return ldapEquality(c)
+	case "!=":
+		s, err := ldapEquality(c)
+		if err != nil {
+			return "", err
+		}
+		return "(!" + s + ")", nil
+	case "<=", ">=":
+		return "(" + k + c.Op() + ldapEscape(c.StringValue()) + ")", nil
+	case "<", ">":
+		v := ldapEscape(c.StringValue())
+		return "(&(" + k + c.Op() + "=" + v + ")(!(" + k + "=" + v + ")))", nil
+	}
+	return "", fmt.Errorf("%s: unsupported operator %s", k, c.Op())
+}
+
+// ldapEquality translates the condition into an equality, presence or
+// substring filter.
+func ldapEquality(c Condition) (string, error) {
+	ts, ok := parseGlob(c.StringValue())
+	if !ok {
+		return "(" + c.Key() + "=" + ldapEscape(c.StringValue()) + ")", nil
+	}
+	sb := strings.Builder{}
+	sb.WriteString("(" + c.Key() + "=")
+	for i, t := range ts {
+		switch {
+		case !t.wildcard:
+			sb.WriteString(ldapEscape(string(t.r)))
+		case t.r == globOne:
+			return "", fmt.Errorf("%s: LDAP filters have no single character wildcard", c.Key())
+		case i == 0 || !ts[i-1].wildcard:
+			// consecutive asterisks are not allowed
+			sb.WriteRune(globAny)
+		}
+	}
+	sb.WriteString(")")
+	return sb.String(), nil
+}
+
+// ldapEscape escapes the characters that are special in LDAP filter values.
+var ldapEscape = strings.NewReplacer(
+	`\`, `\5c`,
+	`*`, `\2a`,
+	`(`, `\28`,
+	`)`, `\29`,
+	"\x00", `\00`,
+).Replace
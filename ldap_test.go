@@ -0,0 +1,99 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToLDAP(t *testing.T) {
+	p := NewParser(OptionOperators("<", ">", "<=", ">=", ":", "~"))
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{"single", "cn=joe", "(cn=joe)", false},
+		{"and", "cn=joe AND dept=eng", "(&(cn=joe)(dept=eng))", false},
+		{"or", "dept=eng OR dept=ops", "(|(dept=eng)(dept=ops))", false},
+		{"mixed", "cn=joe AND dept=eng OR dept=ops", "(&(cn=joe)(|(dept=eng)(dept=ops)))", false},
+		{"not equal", "cn!=joe", "(!(cn=joe))", false},
+		{"has", "memberOf:admins", "(memberOf=admins)", false},
+		{"presence", "mail:*", "(mail=*)", false},
+		{"absence", "mail!=*", "(!(mail=*))", false},
+		{"substring", `cn=jo*n*`, "(cn=jo*n*)", false},
+		{"consecutive wildcards", `cn=**joe`, "(cn=*joe)", false},
+		{"escaped wildcard", `cn=jo\*e`, `(cn=jo\2ae)`, false},
+		{"greater or equal", "uidNumber>=1000", "(uidNumber>=1000)", false},
+		{"less or equal", "uidNumber<=1000", "(uidNumber<=1000)", false},
+		{"less than", "uidNumber<1000", "(&(uidNumber<=1000)(!(uidNumber=1000)))", false},
+		{"greater than", "uidNumber>1000", "(&(uidNumber>=1000)(!(uidNumber=1000)))", false},
+		{"escaping", `cn="a(b)c\\d"`, `(cn=a\28b\29c\5cd)`, false},
+		{"escaping in pattern", `cn="(x)*"`, `(cn=\28x\29*)`, false},
+		{"escaping in ordering", `cn>="*"`, `(cn>=\2a)`, false},
+		{"! empty", "", "", true},
+		{"! single character wildcard", "cn=jo?", "", true},
+		{"! unsupported operator", "cn~joe", "", true},
+		{"! invalid attribute", "foo_bar=1", "", true},
+		{"! dotted key", "foo.bar=1", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got, err := ToLDAP(f)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToLDAP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ToLDAP() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToLDAP_keys(t *testing.T) {
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{"cn", "(cn=x)", false},
+		{"x-custom-1", "(x-custom-1=x)", false},
+		{"2.5.4.3", "(2.5.4.3=x)", false},
+		{"cn;lang-en", "(cn;lang-en=x)", false},
+		{"! cn)(uid=*", "", true},
+		{"! ", "", true},
+		{"! 1cn", "", true},
+		{"! cn;", "", true},
+		{"! c n", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			key := strings.TrimPrefix(tt.key, "! ")
+			f, err := NewFilterFromConditions(separatorAnd, NewCondition(key, []string{key}, "=", "x"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := ToLDAP(f)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToLDAP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ToLDAP() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ldapEscape(t *testing.T) {
+	if got, want := ldapEscape("a*(b)\\\x00"), `a\2a\28b\29\5c\00`; got != want {
+		t.Errorf("ldapEscape() got = %q, want %q", got, want)
+	}
+}
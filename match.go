@@ -0,0 +1,656 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A MatchOption that can be passed to the matching methods of a Filter.
+type MatchOption interface {
+	Apply(cfg *matchConfig)
+}
+
+type matchConfig struct {
+	lenient bool
+	fold    bool
+	missing MissingFieldPolicy
+	schema  map[string]SchemaType
+	tag     string
+}
+
+// newMatchConfig creates a configuration from the options.
+func newMatchConfig(opts []MatchOption) *matchConfig {
+	cfg := &matchConfig{tag: "json"}
+	for _, opt := range opts {
+		opt.Apply(cfg)
+	}
+	return cfg
+}
+
+type matchOptionLenient struct{}
+
+func (o matchOptionLenient) Apply(cfg *matchConfig) {
+	cfg.lenient = true
+}
+
+// MatchOptionLenient will make conditions evaluate to false when their value
+// cannot be converted to the type of the field, instead of returning an error.
+func MatchOptionLenient() MatchOption {
+	return &matchOptionLenient{}
+}
+
+type matchOptionFoldCase struct{}
+
+func (o matchOptionFoldCase) Apply(cfg *matchConfig) {
+	cfg.fold = true
+}
+
+// MatchOptionFoldCase will make the operators '=', '!=' and ':' compare
+// strings case-insensitively (see strings.EqualFold). Other comparisons are
+// not affected. This also applies to quoted condition values.
+func MatchOptionFoldCase() MatchOption {
+	return &matchOptionFoldCase{}
+}
+
+// A MissingFieldPolicy determines how conditions on missing fields evaluate.
+// A field is missing if the record has no value for it, or a null value or
+// an empty list. This includes fields below a missing field.
+type MissingFieldPolicy int
+
+const (
+	// MissingFieldNoMatch makes conditions on missing fields evaluate to
+	// false. This is the default.
+	MissingFieldNoMatch MissingFieldPolicy = iota
+	// MissingFieldMatchNotEqual makes conditions on missing fields evaluate
+	// to true for the '!=' and '!~' operators and to false for all others.
+	MissingFieldMatchNotEqual
+	// MissingFieldError makes conditions on missing fields return an error
+	// wrapping ErrMissingField.
+	MissingFieldError
+)
+
+// ErrMissingField is returned (wrapped) for conditions on missing fields with
+// MissingFieldError.
+var ErrMissingField = errors.New("field is missing")
+
+type matchOptionMissingField MissingFieldPolicy
+
+func (o matchOptionMissingField) Apply(cfg *matchConfig) {
+	cfg.missing = MissingFieldPolicy(o)
+}
+
+// MatchOptionMissingField sets the policy for conditions on missing fields.
+// The default is MissingFieldNoMatch.
+func MatchOptionMissingField(policy MissingFieldPolicy) MatchOption {
+	return matchOptionMissingField(policy)
+}
+
+type matchOptionStructTag string
+
+func (o matchOptionStructTag) Apply(cfg *matchConfig) {
+	cfg.tag = string(o)
+}
+
+// MatchOptionStructTag makes the matcher look up struct fields by the name in
+// the given struct tag, instead of the 'json' tag.
+func MatchOptionStructTag(name string) MatchOption {
+	return matchOptionStructTag(name)
+}
+
+type matchOptionSchema map[string]SchemaType
+
+func (o matchOptionSchema) Apply(cfg *matchConfig) {
+	cfg.schema = o
+}
+
+// MatchOptionSchema declares the types of the fields for the keys in schema.
+// Filter.Compile (and the matching methods) then return an error for
+// conditions on those keys with values that cannot be converted to the type,
+// rather than when evaluating a record, even with MatchOptionLenient.
+func MatchOptionSchema(schema map[string]SchemaType) MatchOption {
+	return matchOptionSchema(schema)
+}
+
+func (f filter) Matches(m map[string]string, opts ...MatchOption) (bool, error) {
+	mt, err := f.compile(opts)
+	if err != nil {
+		return false, err
+	}
+	return mt.evaluate(func(c *compiledCondition) ([]reflect.Value, error) {
+		v, ok := m[c.key]
+		if !ok {
+			return nil, nil
+		}
+		return []reflect.Value{reflect.ValueOf(v)}, nil
+	})
+}
+
+func (f filter) MatchDocument(doc map[string]any, opts ...MatchOption) (bool, error) {
+	m, err := f.compile(opts)
+	if err != nil {
+		return false, err
+	}
+	return m.Match(doc)
+}
+
+func (f filter) MatchStruct(v any, opts ...MatchOption) (bool, error) {
+	m, err := f.compile(opts)
+	if err != nil {
+		return false, err
+	}
+	return m.Match(v)
+}
+
+func (f filter) MatchFunc(resolve func(c Condition) (any, error), opts ...MatchOption) (bool, error) {
+	m, err := f.compile(opts)
+	if err != nil {
+		return false, err
+	}
+	return m.evaluate(func(c *compiledCondition) ([]reflect.Value, error) {
+		v, err := resolve(c.condition)
+		if err != nil {
+			return nil, err
+		}
+		return collect(reflect.ValueOf(v), nil, m.cfg.tag, nil), nil
+	})
+}
+
+// A matcherCache holds the Matcher for a filter without options, so that the
+// matching methods called without options compile the filter only once.
+type matcherCache struct {
+	once sync.Once
+	m    *Matcher
+	err  error
+}
+
+// compile is Compile, but reuses the Matcher if there are no options.
+func (f filter) compile(opts []MatchOption) (*Matcher, error) {
+	if len(opts) > 0 || f.matcher == nil {
+		return f.Compile(opts...)
+	}
+	f.matcher.once.Do(func() {
+		f.matcher.m, f.matcher.err = f.Compile()
+	})
+	return f.matcher.m, f.matcher.err
+}
+
+// A Matcher is a Filter prepared for matching many records. The condition
+// values are converted once, when the Matcher is created by Filter.Compile.
+// A Matcher is safe for concurrent use.
+type Matcher struct {
+	cfg         *matchConfig
+	groups      [][]*compiledCondition
+	comparators map[string]Comparator
+}
+
+// A Comparator compares a field value to a condition value, using the
+// condition's operator. It reports whether the condition holds.
+type Comparator func(op string, fieldValue any, conditionValue string) (bool, error)
+
+// matchOperators are the operators supported by the Matcher.
+var matchOperators = map[string]bool{
+	"=": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true, ":": true,
+	"~": true, "!~": true,
+}
+
+func (f filter) Compile(opts ...MatchOption) (*Matcher, error) {
+	m := &Matcher{cfg: newMatchConfig(opts)}
+	var g []*compiledCondition
+	for c := f.first; c != nil; {
+		next, sep := c.next()
+		if !matchOperators[c.op] {
+			return nil, fmt.Errorf("%s: unsupported operator %s", c.key, c.op)
+		}
+		cc, err := m.cfg.compileCondition(c)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.key, err)
+		}
+		g = append(g, cc)
+		if sep != separatorOr {
+			m.groups = append(m.groups, g)
+			g = nil
+		}
+		c = next
+	}
+	return m, nil
+}
+
+// Matches reports whether the document matches the filter. Documents that
+// cannot be evaluated do not match; use Match to get the error.
+func (m *Matcher) Matches(doc map[string]any) bool {
+	ok, err := m.Match(doc)
+	return ok && err == nil
+}
+
+// Match evaluates the filter against a document or struct (or pointer to
+// either), as described at Filter.MatchDocument and Filter.MatchStruct.
+func (m *Matcher) Match(v any) (bool, error) {
+	rv := reflect.ValueOf(v)
+	return m.evaluate(func(c *compiledCondition) ([]reflect.Value, error) {
+		return collect(rv, c.keyParts, m.cfg.tag, nil), nil
+	})
+}
+
+// WithComparator returns a copy of the Matcher that uses fn to evaluate
+// conditions on key (which may be dotted), instead of the built-in
+// comparisons. The operator is passed as-is, so fn decides which operators it
+// supports. For repeated fields, fn is called for each element and the results
+// are combined as for the built-in comparisons. Errors returned by fn are
+// prefixed with the key.
+func (m *Matcher) WithComparator(key string, fn Comparator) *Matcher {
+	n := *m
+	n.comparators = make(map[string]Comparator, len(m.comparators)+1)
+	for k, v := range m.comparators {
+		n.comparators[k] = v
+	}
+	n.comparators[key] = fn
+	return &n
+}
+
+// evaluate evaluates the OR groups in order, using values to look up the
+// field values for a condition. Evaluation stops at the first OR group that
+// does not match.
+func (m *Matcher) evaluate(values func(c *compiledCondition) ([]reflect.Value, error)) (bool, error) {
+	for _, g := range m.groups {
+		ok := false
+		for _, c := range g {
+			vs, err := values(c)
+			if err != nil {
+				return false, fmt.Errorf("%s: %w", c.key, err)
+			}
+			if len(vs) == 0 {
+				ok, err = m.cfg.matchMissing(c)
+			} else {
+				ok, err = m.cfg.matchValues(c, vs, m.comparators[c.key])
+			}
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				break
+			}
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// A compiledCondition holds a condition along with its value converted to
+// the types it can be compared to.
+type compiledCondition struct {
+	*condition
+	cmpOp string
+	num   float64
+	isNum bool
+	i     int64
+	iErr  error
+	f     float64
+	fErr  error
+	b     bool
+	bErr  error
+	t     time.Time
+	tErr  error
+	d     time.Duration
+	dErr  error
+	glob  []globToken
+	re    *regexp.Regexp
+}
+
+// compileCondition converts the condition value. An error is returned for
+// values that are invalid regular expressions for '~' and '!~', or that
+// cannot be converted to the type declared for the key (see
+// MatchOptionSchema).
+func (cfg *matchConfig) compileCondition(c *condition) (*compiledCondition, error) {
+	cc := &compiledCondition{condition: c, cmpOp: c.op}
+	if c.op == ":" {
+		cc.cmpOp = "="
+	}
+	cc.num, cc.isNum = number(c.stringValue)
+	if cc.i, cc.iErr = strconv.ParseInt(c.stringValue, 10, 64); cc.iErr != nil {
+		cc.iErr = fmt.Errorf("%s is not an integer", c.stringValue)
+	}
+	cc.f, cc.fErr = c.FloatValue()
+	cc.b, cc.bErr = c.BoolValue()
+	cc.t, cc.tErr = c.TimeValue()
+	if cc.d, cc.dErr = time.ParseDuration(c.stringValue); cc.dErr != nil {
+		cc.dErr = fmt.Errorf("%s is not a valid duration", c.stringValue)
+	}
+	if ts, ok := parseGlob(c.stringValue); ok {
+		cc.glob = ts
+	}
+	if c.op == "~" || c.op == "!~" {
+		re, err := c.RegexpValue()
+		if err != nil {
+			return nil, err
+		}
+		cc.re = re
+	}
+	if t, ok := cfg.schema[c.key]; ok {
+		if err := cc.schemaErr(t); err != nil {
+			return nil, err
+		}
+	}
+	return cc, nil
+}
+
+// schemaErr returns the error converting the condition value to t, if any.
+func (c *compiledCondition) schemaErr(t SchemaType) error {
+	switch t {
+	case TypeString:
+		return nil
+	case TypeInt:
+		return c.iErr
+	case TypeFloat:
+		return c.fErr
+	case TypeBool:
+		return c.bErr
+	case TypeTimestamp:
+		return c.tErr
+	}
+	return fmt.Errorf("unsupported schema type %s", t)
+}
+
+// matchValues evaluates the condition against the values found for it, using
+// cmp if it is not nil. The condition matches if it holds for any of the
+// values, except for '!=' and '!~', which must hold for all of them.
+func (cfg *matchConfig) matchValues(c *compiledCondition, vs []reflect.Value, cmp Comparator) (bool, error) {
+	all := c.cmpOp == "!=" || c.cmpOp == "!~"
+	for _, x := range vs {
+		var ok bool
+		var err error
+		if cmp == nil {
+			ok, err = cfg.matchValue(c, x)
+		} else if x.CanInterface() {
+			ok, err = cmp(c.op, x.Interface(), c.stringValue)
+		} else {
+			err = fmt.Errorf("cannot access value of type %s", x.Type())
+		}
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", c.key, err)
+		}
+		if ok != all {
+			return ok, nil
+		}
+	}
+	return all, nil
+}
+
+// matchMissing evaluates a condition on a missing field, according to the
+// MissingFieldPolicy.
+func (cfg *matchConfig) matchMissing(c *compiledCondition) (bool, error) {
+	switch cfg.missing {
+	case MissingFieldMatchNotEqual:
+		return c.op == "!=" || c.op == "!~", nil
+	case MissingFieldError:
+		return false, fmt.Errorf("%s: %w", c.key, ErrMissingField)
+	}
+	return false, nil
+}
+
+// collect appends the values at the path of field names through v to out,
+// looking up struct fields by the given struct tag. Pointers and interfaces
+// are dereferenced and slices are flattened. Nil values are skipped.
+func collect(v reflect.Value, parts []string, tag string, out []reflect.Value) []reflect.Value {
+	v = indirectAll(v)
+	if !v.IsValid() {
+		return out
+	}
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8 {
+		for i := 0; i < v.Len(); i += 1 {
+			out = collect(v.Index(i), parts, tag, out)
+		}
+		return out
+	}
+	if len(parts) == 0 {
+		return append(out, v)
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() == reflect.String {
+			if e := v.MapIndex(reflect.ValueOf(parts[0]).Convert(v.Type().Key())); e.IsValid() {
+				return collect(e, parts[1:], tag, out)
+			}
+		}
+	case reflect.Struct:
+		if v.Type() != timeType {
+			if field, ok := taggedField(v, tag, parts[0]); ok {
+				return collect(field, parts[1:], tag, out)
+			}
+		}
+	}
+	return out
+}
+
+// taggedField looks up the exported field matching name. A field matches when
+// the name in its struct tag (like 'json') equals the name. Failing that, a
+// field without such a name matches when its own name equals the name
+// (case-insensitive). Fields of embedded structs are searched last.
+func taggedField(v reflect.Value, tagName, name string) (reflect.Value, bool) {
+	t := v.Type()
+	var embedded []reflect.Value
+	var byName reflect.Value
+	for i := 0; i < t.NumField(); i += 1 {
+		sf := t.Field(i)
+		tag, _, _ := strings.Cut(sf.Tag.Get(tagName), ",")
+		if tag == "-" {
+			continue
+		}
+		if sf.Anonymous && tag == "" {
+			if e := indirect(v.Field(i)); e.Kind() == reflect.Struct {
+				embedded = append(embedded, e)
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if tag == name {
+			return v.Field(i), true
+		}
+		if tag == "" && !byName.IsValid() && strings.EqualFold(sf.Name, name) {
+			byName = v.Field(i)
+		}
+	}
+	if byName.IsValid() {
+		return byName, true
+	}
+	for _, e := range embedded {
+		if field, ok := taggedField(e, tagName, name); ok {
+			return field, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// indirectAll dereferences pointers and interfaces until it reaches another
+// kind of value. A nil value results in an invalid Value.
+func indirectAll(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// matchValue compares a single field value to the condition value, according
+// to the field's type.
+func (cfg *matchConfig) matchValue(c *compiledCondition, v reflect.Value) (bool, error) {
+	switch v.Type() {
+	case timeType:
+		if c.tErr != nil {
+			return cfg.mismatch(c.tErr)
+		}
+		return compareTimes(c.cmpOp, v.Interface().(time.Time), c.t)
+	case durationType:
+		if c.dErr != nil {
+			return cfg.mismatch(c.dErr)
+		}
+		return compareOrdered(c.cmpOp, v.Int(), int64(c.d))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return cfg.matchString(c, v.String())
+	case reflect.Bool:
+		if c.bErr != nil {
+			return cfg.mismatch(c.bErr)
+		}
+		return compareBools(c.cmpOp, v.Bool(), c.b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if c.iErr == nil {
+			return compareOrdered(c.cmpOp, v.Int(), c.i)
+		}
+		return cfg.matchFloat(c, float64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if c.iErr == nil {
+			return compareUint(c.cmpOp, v.Uint(), c.i)
+		}
+		return cfg.matchFloat(c, float64(v.Uint()))
+	case reflect.Float32, reflect.Float64:
+		return cfg.matchFloat(c, v.Float())
+	}
+	if v.CanInterface() {
+		if s, ok := v.Interface().(fmt.Stringer); ok {
+			return cfg.matchString(c, s.String())
+		}
+	}
+	return false, fmt.Errorf("unsupported type %s", v.Type())
+}
+
+// matchFloat compares a numeric field value to the condition value.
+func (cfg *matchConfig) matchFloat(c *compiledCondition, x float64) (bool, error) {
+	if c.fErr != nil {
+		return cfg.mismatch(c.fErr)
+	}
+	return compareOrdered(c.cmpOp, x, c.f)
+}
+
+// mismatch handles a condition value that cannot be converted to the type of
+// the field.
+func (cfg *matchConfig) mismatch(err error) (bool, error) {
+	if cfg.lenient {
+		return false, nil
+	}
+	return false, err
+}
+
+// compareTimes applies the comparison operator to the field value and the
+// condition value.
+func compareTimes(op string, field, value time.Time) (bool, error) {
+	cmp := 0
+	if field.Before(value) {
+		cmp = -1
+	} else if field.After(value) {
+		cmp = 1
+	}
+	return compareOrdered(op, cmp, 0)
+}
+
+// matchString compares a string field value to the condition value. Equality
+// is exact, unless case folding is enabled. A condition value with wildcards
+// is matched as a glob pattern: '*' matches any sequence of characters and '?'
+// a single character; a backslash escapes either. The ordering operators
+// compare chronologically if both values are timestamps, numerically if both
+// are numbers and lexicographically otherwise. The operators '~' and '!~' report whether the
+// regular expression matches (part of) the value, or not.
+func (cfg *matchConfig) matchString(c *compiledCondition, v string) (bool, error) {
+	switch c.cmpOp {
+	case "~":
+		return c.re.MatchString(v), nil
+	case "!~":
+		return !c.re.MatchString(v), nil
+	case "=":
+		if c.glob != nil {
+			return matchGlob(c.glob, v, cfg.fold), nil
+		}
+		if cfg.fold {
+			return strings.EqualFold(v, c.stringValue), nil
+		}
+	case "!=":
+		if c.glob != nil {
+			return !matchGlob(c.glob, v, cfg.fold), nil
+		}
+		if cfg.fold {
+			return !strings.EqualFold(v, c.stringValue), nil
+		}
+	case "<", ">", "<=", ">=":
+		if c.tErr == nil {
+			if t, ok := parseTime(v); ok {
+				return compareTimes(c.cmpOp, t, c.t)
+			}
+		}
+		if x, isNum := number(v); isNum && c.isNum {
+			return compareOrdered(c.cmpOp, x, c.num)
+		}
+	}
+	return compareOrdered(c.cmpOp, v, c.stringValue)
+}
+
+// FilterSlice returns the elements of xs that match the filter. The elements
+// can be structs or maps (or pointers to either); see Filter.MatchStruct and
+// Filter.MatchDocument. The filter is compiled once. If an element cannot be
+// evaluated, an error mentioning its index is returned.
+func FilterSlice[T any](xs []T, f Filter, opts ...MatchOption) ([]T, error) {
+	m, err := f.Compile(opts...)
+	if err != nil {
+		return nil, err
+	}
+	var out []T
+	for i, x := range xs {
+		ok, err := m.Match(x)
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", i, err)
+		}
+		if ok {
+			out = append(out, x)
+		}
+	}
+	return out, nil
+}
+
+// ApplyFilter returns an Iterator that only yields the elements of it that
+// match the filter, like FilterSlice. The filter is compiled once. Errors of
+// it, including Done, are passed on unchanged. If an element cannot be
+// evaluated, an error mentioning its (zero-based) index in it is returned.
+func ApplyFilter[T any](it Iterator[T], f Filter, opts ...MatchOption) Iterator[T] {
+	m, err := f.Compile(opts...)
+	i := -1
+	return iteratorFunc[T](func() (T, error) {
+		var zero T
+		if err != nil {
+			return zero, err
+		}
+		for {
+			x, err := it.Next()
+			if err != nil {
+				return x, err
+			}
+			i += 1
+			ok, err := m.Match(x)
+			if err != nil {
+				return zero, fmt.Errorf("element %d: %w", i, err)
+			}
+			if ok {
+				return x, nil
+			}
+		}
+	})
+}
@@ -0,0 +1,872 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFilter_Matches(t *testing.T) {
+	p := NewParser(OptionOperators("<", ">", "<=", ">=", "%"))
+	m := map[string]string{
+		"status":  "open",
+		"size":    "9",
+		"version": "v10",
+		"app.env": "prod",
+		"created": "2024-03-01T12:00:00Z",
+	}
+	tests := []struct {
+		name    string
+		query   string
+		want    bool
+		wantErr bool
+	}{
+		{"empty", "", true, false},
+		{"equal", "status=open", true, false},
+		{"not equal", "status!=open", false, false},
+		{"dotted key", "app.env=prod", true, false},
+		{"and", "status=open AND app.env=prod AND size=9", true, false},
+		{"and, one fails", "status=open AND app.env=dev", false, false},
+		{"or rescue", "status=closed OR status=open AND size=9", true, false},
+		{"or, none match", "status=closed OR status=pending AND size=9", false, false},
+		{"missing key", "owner=me", false, false},
+		{"missing key, not equal", "owner!=me", false, false},
+		{"missing key, or rescue", "owner=me OR status=open", true, false},
+		{"numeric", "size<10", true, false},
+		{"numeric, boundary", "size>=9", true, false},
+		{"numeric, strict boundary", "size>9", false, false},
+		{"numeric, not lexicographic", "size>10", false, false},
+		{"lexicographic", "version<v9", true, false},
+		{"mixed, numeric field", "size<abc", true, false},
+		{"mixed, numeric value", "status<10", false, false},
+		{"time", "created>2024-01-01", true, false},
+		{"time, boundary", "created>=2024-03-01T12:00:00Z", true, false},
+		{"time, strict boundary", "created>2024-03-01T12:00:00Z", false, false},
+		{"time, other time zone", "created<=2024-03-01T13:00:00+01:00", true, false},
+		{"time, other time zone, strict", "created<2024-03-01T13:00:00+01:00", false, false},
+		{"time, not lexicographic", "created<2024-03-01T12:30:00+02:00", false, false},
+		{"numeric equality is exact", "size=9.0", false, false},
+		{"! unsupported operator", "status%open", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got, err := f.Matches(m)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Matches() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Matches() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter_Matches_compiledOnce(t *testing.T) {
+	f, _ := NewParser(OptionOperators(">")).Parse("n>9 AND name=foo")
+	m := map[string]string{"n": "10", "name": "foo"}
+	for i := 0; i < 2; i += 1 {
+		if ok, err := f.Matches(m); !ok || err != nil {
+			t.Fatalf("Matches() got = %v, %v, want true", ok, err)
+		}
+	}
+	mt := f.(filter).matcher.m
+	if mt == nil {
+		t.Fatalf("expected compiled matcher")
+	}
+	if ok, err := f.MatchMap(m); !ok || err != nil {
+		t.Errorf("MatchMap() got = %v, %v, want true", ok, err)
+	}
+	if f.(filter).matcher.m != mt {
+		t.Errorf("expected matcher to be reused")
+	}
+	if _, err := f.Matches(m, MatchOptionLenient()); err != nil || f.(filter).matcher.m != mt {
+		t.Errorf("expected matcher with options not to be cached")
+	}
+}
+
+func TestFilter_MatchDocument(t *testing.T) {
+	p := NewParser(OptionOperators("<", ">", "<=", ">="))
+	doc := map[string]any{
+		"name":    "foo",
+		"size":    float64(10),
+		"count":   3,
+		"active":  true,
+		"created": time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		"empty":   nil,
+		"tags":    []any{"a", "b"},
+		"a": map[string]any{
+			"b": map[string]any{
+				"c": map[string]any{"d": "deep"},
+			},
+		},
+		"items": []any{
+			map[string]any{"sku": "abc", "qty": float64(1)},
+			map[string]any{"sku": "def", "qty": float64(5)},
+		},
+		"labels":  map[string]string{"env": "prod"},
+		"timeout": 90 * time.Second,
+		"updated": "2024-03-01T13:00:00+01:00",
+	}
+	tests := []struct {
+		name    string
+		query   string
+		opts    []MatchOption
+		want    bool
+		wantErr bool
+	}{
+		{"empty", "", nil, true, false},
+		{"string", "name=foo", nil, true, false},
+		{"float", "size=10", nil, true, false},
+		{"float, decimal", "size=10.0", nil, true, false},
+		{"float, ordering", "size>9.5", nil, true, false},
+		{"int", "count<=3", nil, true, false},
+		{"int, float value", "count<3.5", nil, true, false},
+		{"bool", "active=true", nil, true, false},
+		{"bool, not equal", "active!=true", nil, false, false},
+		{"time", "created>2024-01-01", nil, true, false},
+		{"time, timestamp", "created=2024-03-01T13:00:00+01:00", nil, true, false},
+		{"null", "empty=foo", nil, false, false},
+		{"missing", "missing=foo", nil, false, false},
+		{"missing, intermediate", "x.y=foo", nil, false, false},
+		{"three levels", "a.b.c.d=deep", nil, true, false},
+		{"three levels, no match", "a.b.c.d=shallow", nil, false, false},
+		{"path through string", "name.x=foo", nil, false, false},
+		{"slice", "tags=b", nil, true, false},
+		{"slice, no match", "tags=c", nil, false, false},
+		{"slice of maps", "items.sku=def", nil, true, false},
+		{"slice of maps, ordering", "items.qty>3", nil, true, false},
+		{"slice of maps, no match", "items.qty>5", nil, false, false},
+		{"typed map", "labels.env=prod", nil, true, false},
+		{"duration", "timeout>1m", nil, true, false},
+		{"duration, equal", "timeout=1m30s", nil, true, false},
+		{"! duration mismatch", "timeout>1", nil, false, true},
+		{"and, or", "name=bar OR size>5 AND tags=a", nil, true, false},
+		{"! number mismatch", "size=abc", nil, false, true},
+		{"! bool mismatch", "active=yes", nil, false, true},
+		{"! time mismatch", "created>yesterday", nil, false, true},
+		{"time, boundary", "created>=2024-03-01T12:00:00Z", nil, true, false},
+		{"time, strict boundary", "created>2024-03-01T12:00:00Z", nil, false, false},
+		{"time string", "updated>=2024-03-01T12:00:00Z", nil, true, false},
+		{"time string, strict boundary", "updated>2024-03-01T12:00:00Z", nil, false, false},
+		{"time string, date", "updated<2024-03-02", nil, true, false},
+		{"mixed number and string", "name>10", nil, true, false},
+		{"lenient number", "size=abc", []MatchOption{MatchOptionLenient()}, false, false},
+		{"lenient, mixed number and string", "name<10", []MatchOption{MatchOptionLenient()}, false, false},
+		{"lenient, or rescue", "active=yes OR name=foo", []MatchOption{MatchOptionLenient()}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got, err := f.MatchDocument(doc, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MatchDocument() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("MatchDocument() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter_MatchStruct(t *testing.T) {
+	type Owner struct {
+		ID   int    `json:"id"`
+		Name string `json:"name,omitempty"`
+	}
+	type Base struct {
+		Kind    string
+		Created time.Time
+	}
+	type Item struct {
+		SKU string `json:"sku"`
+		Qty uint
+	}
+	type Resource struct {
+		Base
+		Title    string `json:"label"`
+		Label    string
+		Size     float32
+		Active   bool
+		Owner    *Owner
+		Previous *Owner
+		Items    []Item
+		Tags     []string
+		Labels   map[string]string
+		Ch       chan int
+		Hidden   string `json:"-"`
+		private  string
+	}
+	owner := &Owner{42, "joe"}
+	obj := &Resource{
+		Base:   Base{"doc", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		Title:  "title",
+		Label:  "label",
+		Size:   1.5,
+		Active: true,
+		Owner:  owner,
+		Items:  []Item{{"abc", 1}, {"def", 5}},
+		Tags:   []string{"a", "b"},
+		Labels: map[string]string{"env": "prod"},
+		Hidden: "hidden",
+	}
+	ptr := &obj
+	p := NewParser(OptionOperators("<", ">", "<=", ">="))
+	tests := []struct {
+		name    string
+		query   string
+		obj     any
+		want    bool
+		wantErr bool
+	}{
+		{"field name", "active=true", obj, true, false},
+		{"field name, case-insensitive", "ACTIVE=true", obj, true, false},
+		{"tag", "owner.id=42", obj, true, false},
+		{"tag with options", "owner.name=joe", obj, true, false},
+		{"tag over field name", "label=title", obj, true, false},
+		{"tagged field not by name", "title=title", obj, false, false},
+		{"float32", "size>1", obj, true, false},
+		{"uint", "items.qty>=5", obj, true, false},
+		{"embedded", "kind=doc", obj, true, false},
+		{"embedded time", "created>=2024-03-01", obj, true, false},
+		{"slice of structs", "items.sku=def", obj, true, false},
+		{"slice", "tags=a", obj, true, false},
+		{"map", "labels.env=prod", obj, true, false},
+		{"nil pointer", "previous.id=42", obj, false, false},
+		{"pointer to pointer", "owner.id=42", ptr, true, false},
+		{"not a pointer", "kind=doc", *obj, true, false},
+		{"skipped", "hidden=hidden", obj, false, false},
+		{"unexported", "private=", obj, false, false},
+		{"missing", "missing=1", obj, false, false},
+		{"nil", "missing=1", nil, false, false},
+		{"! unsupported", "ch=1", obj, false, true},
+		{"! mismatch", "owner.id=joe", obj, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got, err := f.MatchStruct(tt.obj)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MatchStruct() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("MatchStruct() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter_MatchStruct_errorPath(t *testing.T) {
+	type Inner struct {
+		Ch chan int
+	}
+	f, err := NewParser().Parse("inner.ch=1")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	_, err = f.MatchStruct(struct{ Inner Inner }{Inner{make(chan int)}})
+	if err == nil || !strings.HasPrefix(err.Error(), "inner.ch: ") {
+		t.Errorf("MatchStruct() error = %v, want error for inner.ch", err)
+	}
+}
+
+func TestFilter_MatchFunc(t *testing.T) {
+	record := map[string]any{"foo": "bar", "tags": []string{"a", "b"}}
+	resolve := func(c Condition) (any, error) {
+		if c.Key() == "fail" {
+			return nil, errTest
+		}
+		return record[c.Key()], nil
+	}
+	tests := []struct {
+		query   string
+		want    bool
+		wantErr bool
+	}{
+		{"foo=bar", true, false},
+		{"foo=baz", false, false},
+		{"tags=b", true, false},
+		{"missing=1", false, false},
+		{"fail=1", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			f, err := NewParser().Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got, err := f.MatchFunc(resolve)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MatchFunc() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("MatchFunc() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter_Compile(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		docs    []map[string]any
+		want    []bool
+		wantErr bool
+	}{
+		{"empty", "", []map[string]any{{}, {"foo": "bar"}}, []bool{true, true}, false},
+		{
+			"and, or",
+			"size>5 AND kind=a OR kind=b",
+			[]map[string]any{
+				{"size": 6, "kind": "a"},
+				{"size": 6.5, "kind": "b"},
+				{"size": 5, "kind": "a"},
+				{"size": 6, "kind": "c"},
+				{"kind": "a"},
+			},
+			[]bool{true, true, false, false, false},
+			false,
+		},
+		{"mismatch does not match", "size=abc", []map[string]any{{"size": 1}}, []bool{false}, false},
+		{
+			"regexp",
+			"name~^fo+$",
+			[]map[string]any{{"name": "foo"}, {"name": "bar"}, {"name": []any{"x", "fooo"}}, {}},
+			[]bool{true, false, true, false},
+			false,
+		},
+		{
+			"regexp, partial",
+			"name~o",
+			[]map[string]any{{"name": "foo"}, {"name": "bar"}},
+			[]bool{true, false},
+			false,
+		},
+		{
+			"not regexp",
+			"name!~^fo+$",
+			[]map[string]any{{"name": "foo"}, {"name": "bar"}, {"name": []any{"bar", "foo"}}},
+			[]bool{false, true, false},
+			false,
+		},
+		{"! invalid regexp", "name~(", nil, nil, true},
+		{"! unsupported operator", "name%foo", nil, nil, true},
+	}
+	p := NewParser(OptionOperators("<", ">", "~", "!~", "%"))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			m, err := f.Compile()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for i, doc := range tt.docs {
+				if got := m.Matches(doc); got != tt.want[i] {
+					t.Errorf("Matches(%v) got = %v, want %v", doc, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFilter_Compile_schema(t *testing.T) {
+	p := NewParser(OptionOperators("<", ">"))
+	schema := MatchOptionSchema(map[string]SchemaType{
+		"size": TypeInt, "ratio": TypeFloat, "ok": TypeBool, "at": TypeTimestamp, "name": TypeString,
+	})
+	tests := []struct {
+		query   string
+		wantErr bool
+	}{
+		{"size>5 AND ratio<0.5 AND ok=true AND at>2022-01-01 AND name=x", false},
+		{"other=abc", false},
+		{"! size=abc", true},
+		{"! size=1.5", true},
+		{"! ratio=abc", true},
+		{"! ok=yes", true},
+		{"! at>yesterday", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			f, err := p.Parse(strings.TrimPrefix(tt.query, "! "))
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			_, err = f.Compile(schema, MatchOptionLenient())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMatcher_Match(t *testing.T) {
+	type Item struct {
+		Name string
+		Size int
+	}
+	f, err := NewParser(OptionOperators(">")).Parse("name=foo AND size>1")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	m, err := f.Compile()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, tt := range []struct {
+		v       any
+		want    bool
+		wantErr bool
+	}{
+		{Item{"foo", 2}, true, false},
+		{&Item{"foo", 1}, false, false},
+		{map[string]any{"name": "foo", "size": 2}, true, false},
+		{map[string]any{"name": "foo", "size": []int{1, 3}}, true, false},
+		{map[string]any{"name": "foo", "size": "0"}, false, false},
+		{map[string]any{"name": "foo", "size": true}, false, true},
+	} {
+		got, err := m.Match(tt.v)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Match(%v) error = %v, wantErr %v", tt.v, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("Match(%v) got = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
+
+func benchmarkRecords(n int) []map[string]any {
+	docs := make([]map[string]any, n)
+	for i := range docs {
+		docs[i] = map[string]any{
+			"name":    fmt.Sprintf("item-%d", i),
+			"size":    float64(i % 100),
+			"created": time.Date(2024, 1, 1+i%365, 0, 0, 0, 0, time.UTC),
+			"owner":   map[string]any{"id": float64(i % 10)},
+		}
+	}
+	return docs
+}
+
+func BenchmarkMatcher(b *testing.B) {
+	docs := benchmarkRecords(10000)
+	p := NewParser(OptionOperators("<", ">=", ">"))
+	f, err := p.Parse("size>=50 AND created>2024-06-01 AND owner.id=3 OR owner.id=4")
+	if err != nil {
+		b.Fatalf("unexpected parse error: %v", err)
+	}
+	b.Run("per record", func(b *testing.B) {
+		for i := 0; i < b.N; i += 1 {
+			for _, doc := range docs {
+				_, _ = f.MatchDocument(doc)
+			}
+		}
+	})
+	b.Run("compiled", func(b *testing.B) {
+		m, err := f.Compile()
+		if err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+		for i := 0; i < b.N; i += 1 {
+			for _, doc := range docs {
+				_ = m.Matches(doc)
+			}
+		}
+	})
+}
+
+func TestFilterSlice(t *testing.T) {
+	type Owner struct {
+		Name string
+	}
+	type Item struct {
+		Name  string
+		Price float64
+		Owner *Owner
+	}
+	items := []Item{
+		{"a", 1.5, &Owner{"joe"}},
+		{"b", 10, &Owner{"ann"}},
+		{"c", 5, nil},
+		{"d", 7.5, &Owner{"joe"}},
+	}
+	p := NewParser(OptionOperators("<", ">="))
+	tests := []struct {
+		name    string
+		query   string
+		want    []string
+		wantErr bool
+	}{
+		{"all", "", []string{"a", "b", "c", "d"}, false},
+		{"nested field", "owner.name=joe", []string{"a", "d"}, false},
+		{"numeric range", "price>=5 AND price<10", []string{"c", "d"}, false},
+		{"none", "name=x", nil, false},
+		{"! mismatch", "price=cheap", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got, err := FilterSlice(items, f)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FilterSlice() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var names []string
+			for _, x := range got {
+				names = append(names, x.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("FilterSlice() got = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterSlice_maps(t *testing.T) {
+	docs := []map[string]any{{"size": 1}, {"size": 2}, {"size": "large"}, {"size": true}}
+	f, err := NewParser(OptionOperators(">")).Parse("size>1")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	_, err = FilterSlice(docs, f)
+	if err == nil || !strings.HasPrefix(err.Error(), "element 3: ") {
+		t.Errorf("FilterSlice() error = %v, want error for element 3", err)
+	}
+	got, err := FilterSlice(docs[:2], f)
+	if err != nil || !reflect.DeepEqual(got, docs[1:2]) {
+		t.Errorf("FilterSlice() got = %v, %v, want %v", got, err, docs[1:2])
+	}
+}
+
+func TestApplyFilter(t *testing.T) {
+	type Item struct {
+		Name string
+		Size int
+	}
+	p := NewParser(OptionOperators(">", "%"))
+	tests := []struct {
+		name    string
+		query   string
+		it      Iterator[Item]
+		want    []Item
+		wantErr string
+	}{
+		{"empty", "size>1", ForSlice[Item](nil), nil, ""},
+		{"some", "size>1", ForSlice([]Item{{"a", 1}, {"b", 2}, {"c", 3}}), []Item{{"b", 2}, {"c", 3}}, ""},
+		{"source error", "size>1", errIterator(errTest, Item{"a", 1}, Item{"b", 2}), []Item{{"b", 2}}, errTest.Error()},
+		{"match error", "size>x", ForSlice([]Item{{"a", 1}, {"b", 2}}), nil, "element 0: size: x is not a valid float"},
+		{"compile error", "name%a", ForSlice([]Item{{"a", 1}}), nil, "name: unsupported operator %"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got, err := readAll(ApplyFilter(tt.it, f))
+			if (err != nil || tt.wantErr != "") && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("ApplyFilter() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ApplyFilter() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyFilter_done(t *testing.T) {
+	f, err := NewParser().Parse("name=a")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	it := ApplyFilter(ForSlice([]map[string]any{{"name": "b"}, {"name": "a"}, {"name": "c"}}), f)
+	if x, err := it.Next(); err != nil || x["name"] != "a" {
+		t.Errorf("Next() got = %v, %v, want name=a", x, err)
+	}
+	for i := 0; i < 2; i += 1 {
+		if _, err := it.Next(); err != Done {
+			t.Errorf("Next() after end got = %v, want Done", err)
+		}
+	}
+}
+
+func TestApplyFilter_errorMidStream(t *testing.T) {
+	f, err := NewParser(OptionOperators(">")).Parse("size>1")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	docs := []map[string]any{{"size": 1}, {"size": 2}, {"size": true}, {"size": 3}}
+	got, err := readAll(ApplyFilter(ForSlice(docs), f))
+	if err == nil || !strings.HasPrefix(err.Error(), "element 2: ") {
+		t.Errorf("ApplyFilter() error = %v, want error for element 2", err)
+	}
+	if !reflect.DeepEqual(got, docs[1:2]) {
+		t.Errorf("ApplyFilter() got = %v, want %v", got, docs[1:2])
+	}
+}
+
+func TestMatchOptionFoldCase(t *testing.T) {
+	type Item struct {
+		Status string
+		Tags   []string
+	}
+	p := NewParser(OptionOperators(":", "<"))
+	tests := []struct {
+		query    string
+		want     bool
+		wantFold bool
+	}{
+		{"status=open", false, true},
+		{`status="OPEN"`, true, true},
+		{"status!=open", true, false},
+		{"status=closed", false, false},
+		{"tags:urgent", false, true},
+		{"status<b", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			m := map[string]string{"status": "OPEN", "tags": "URGENT"}
+			doc := map[string]any{"status": "OPEN", "tags": []any{"low", "URGENT"}}
+			obj := Item{"OPEN", []string{"low", "URGENT"}}
+			for _, fold := range []bool{false, true} {
+				var opts []MatchOption
+				want := tt.want
+				if fold {
+					opts = append(opts, MatchOptionFoldCase())
+					want = tt.wantFold
+				}
+				if got, err := f.Matches(m, opts...); err != nil || got != want {
+					t.Errorf("Matches() with fold %v got = %v, %v, want %v", fold, got, err, want)
+				}
+				if got, err := f.MatchDocument(doc, opts...); err != nil || got != want {
+					t.Errorf("MatchDocument() with fold %v got = %v, %v, want %v", fold, got, err, want)
+				}
+				if got, err := f.MatchStruct(obj, opts...); err != nil || got != want {
+					t.Errorf("MatchStruct() with fold %v got = %v, %v, want %v", fold, got, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchOptionMissingField(t *testing.T) {
+	type Owner struct {
+		Name string
+	}
+	type Item struct {
+		Status string
+		Owner  *Owner
+		Tags   []string
+	}
+	p := NewParser(OptionOperators(":", "<"))
+	tests := []struct {
+		query        string
+		wantNoMatch  bool
+		wantNotEqual bool
+		wantErr      bool
+	}{
+		{"status=open", true, true, false},
+		{"missing=x", false, false, true},
+		{"missing!=x", false, true, true},
+		{"missing<x", false, false, true},
+		{"owner.name=me", false, false, true},
+		{"owner.name!=me", false, true, true},
+		{"tags:urgent", false, false, true},
+		{"tags!=urgent", false, true, true},
+		{"missing!=x AND status=open", false, true, true},
+		{"missing=x OR status=open", true, true, true},
+		{"status=open OR missing=x", true, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			m := map[string]string{"status": "open"}
+			doc := map[string]any{"status": "open", "owner": nil, "tags": []any{}}
+			obj := Item{Status: "open"}
+			policies := []struct {
+				policy  MissingFieldPolicy
+				want    bool
+				wantErr bool
+			}{
+				{MissingFieldNoMatch, tt.wantNoMatch, false},
+				{MissingFieldMatchNotEqual, tt.wantNotEqual, false},
+				{MissingFieldError, tt.wantNoMatch && !tt.wantErr, tt.wantErr},
+			}
+			for _, pp := range policies {
+				opt := MatchOptionMissingField(pp.policy)
+				if got, err := f.Matches(m, opt); (err != nil) != pp.wantErr || got != pp.want {
+					t.Errorf("Matches() with policy %d got = %v, %v, want %v", pp.policy, got, err, pp.want)
+				}
+				if got, err := f.MatchDocument(doc, opt); (err != nil) != pp.wantErr || got != pp.want {
+					t.Errorf("MatchDocument() with policy %d got = %v, %v, want %v", pp.policy, got, err, pp.want)
+				}
+				if got, err := f.MatchStruct(obj, opt); (err != nil) != pp.wantErr || got != pp.want {
+					t.Errorf("MatchStruct() with policy %d got = %v, %v, want %v", pp.policy, got, err, pp.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMatcher_repeated(t *testing.T) {
+	type Item struct {
+		Sku string
+		Qty int
+	}
+	type Record struct {
+		Tags   []string
+		Items  []Item
+		Groups [][]string
+		Empty  []string
+	}
+	rec := Record{
+		Tags:   []string{"urgent", "bug"},
+		Items:  []Item{{"abc", 1}, {"def", 5}},
+		Groups: [][]string{{"a", "b"}, {"c"}},
+	}
+	p := NewParser(OptionOperators(":", ">", "!~"))
+	tests := []struct {
+		query   string
+		policy  MissingFieldPolicy
+		want    bool
+		wantErr bool
+	}{
+		{"tags=urgent", MissingFieldNoMatch, true, false},
+		{"tags:bug", MissingFieldNoMatch, true, false},
+		{"tags=feature", MissingFieldNoMatch, false, false},
+		{"tags!=urgent", MissingFieldNoMatch, false, false},
+		{"tags!=feature", MissingFieldNoMatch, true, false},
+		{"items.sku=abc", MissingFieldNoMatch, true, false},
+		{"items.sku!=abc", MissingFieldNoMatch, false, false},
+		{"items.sku!=xyz", MissingFieldNoMatch, true, false},
+		{"items.qty>3", MissingFieldNoMatch, true, false},
+		{"items.qty>5", MissingFieldNoMatch, false, false},
+		{"groups=c", MissingFieldNoMatch, true, false},
+		{"groups!=c", MissingFieldNoMatch, false, false},
+		{"groups!=d", MissingFieldNoMatch, true, false},
+		{"empty=x", MissingFieldNoMatch, false, false},
+		{"empty!=x", MissingFieldNoMatch, false, false},
+		{"empty=x", MissingFieldMatchNotEqual, false, false},
+		{"empty!=x", MissingFieldMatchNotEqual, true, false},
+		{"empty!=x", MissingFieldError, false, true},
+		{"empty!~x", MissingFieldMatchNotEqual, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got, err := f.MatchStruct(rec, MatchOptionMissingField(tt.policy))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MatchStruct() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrMissingField) {
+				t.Errorf("MatchStruct() error = %v, want ErrMissingField", err)
+			}
+			if got != tt.want {
+				t.Errorf("MatchStruct() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatcher_WithComparator(t *testing.T) {
+	semver := func(op string, fieldValue any, conditionValue string) (bool, error) {
+		s, ok := fieldValue.(string)
+		if !ok {
+			return false, fmt.Errorf("expected string, got %T", fieldValue)
+		}
+		v, err := ParseVersion(s)
+		if err != nil {
+			return false, err
+		}
+		w, err := ParseVersion(conditionValue)
+		if err != nil {
+			return false, err
+		}
+		return compareOrdered(op, v.Compare(w), 0)
+	}
+	doc := map[string]any{
+		"version": "1.10.0",
+		"name":    "foo",
+		"size":    float64(10),
+		"app":     map[string]any{"version": "2.0.0-rc.1", "versions": []any{"1.2.0", "1.10.0"}},
+	}
+	p := NewParser(OptionOperators("<", ">", "<=", ">=", "%"))
+	tests := []struct {
+		name    string
+		query   string
+		want    bool
+		wantErr string
+	}{
+		{"semantic", "version>1.9.0", true, ""},
+		{"semantic, equal", "version>=1.10.0", true, ""},
+		{"semantic, strict", "version>1.10.0", false, ""},
+		{"dotted key", "app.version<2.0.0", true, ""},
+		{"repeated", "app.versions>1.9.0", true, ""},
+		{"repeated, not equal", "app.versions!=1.2.0", false, ""},
+		{"built-in string", "name=foo", true, ""},
+		{"built-in number", "size>9", true, ""},
+		{"! unsupported operator", "version%1", false, "version: unsupported operator %"},
+		{"! comparator error", "version=x", false, "version: x is not a valid version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			m, err := f.Compile()
+			if err != nil {
+				if tt.wantErr == "" || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Compile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			orig := m
+			m = m.WithComparator("version", semver).
+				WithComparator("app.version", semver).
+				WithComparator("app.versions", semver)
+			if orig.comparators != nil {
+				t.Errorf("WithComparator() modified the original Matcher")
+			}
+			got, err := m.Match(doc)
+			if (err != nil) != (tt.wantErr != "") || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Match() error = %v, want %q", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Match() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
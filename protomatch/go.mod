@@ -0,0 +1,10 @@
+module github.com/HayoVanLoon/go-listfilter/protomatch
+
+go 1.23
+
+require (
+	github.com/HayoVanLoon/go-listfilter v0.0.0-00010101000000-000000000000
+	google.golang.org/protobuf v1.36.12
+)
+
+replace github.com/HayoVanLoon/go-listfilter => ../
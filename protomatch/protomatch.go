@@ -0,0 +1,189 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+// Package protomatch evaluates filters against protocol buffer messages. It is
+// a separate module, so that the listfilter package itself does not depend on
+// the protobuf runtime.
+package protomatch
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HayoVanLoon/go-listfilter"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// MatchProto evaluates the filter against the message, with the same semantics
+// as Filter.MatchDocument.
+//
+// Key parts are resolved to fields by their proto (snake_case) name or, failing
+// that, their JSON (camelCase) name. Parsing with listfilter.OptionSnakeCase
+// therefore works for both. Map fields are addressed by key part (like
+// 'labels.env') and conditions on repeated fields match if any of the elements
+// does, or for '!=', if none of them equals the value. Enums are compared by
+// name, unless the condition value is a number. Timestamps and durations are
+// compared as time.Time and time.Duration.
+// Fields below unset message fields, missing map keys and empty repeated
+// fields are missing (see listfilter.MissingFieldPolicy). An unknown field
+// name results in an error listing the valid names.
+func MatchProto(m proto.Message, f listfilter.Filter, opts ...listfilter.MatchOption) (bool, error) {
+	msg := m.ProtoReflect()
+	return f.MatchFunc(func(c listfilter.Condition) (any, error) {
+		return resolve(msg, c.KeyParts(), c.StringValue())
+	}, opts...)
+}
+
+// resolve returns the value at the path of field names through the message.
+// Values of repeated fields are returned as a slice.
+func resolve(m protoreflect.Message, parts []string, value string) (any, error) {
+	if !m.IsValid() || len(parts) == 0 {
+		return nil, nil
+	}
+	fd, err := field(m.Descriptor(), parts[0])
+	if err != nil {
+		return nil, err
+	}
+	rest := parts[1:]
+	switch {
+	case fd.IsList():
+		l := m.Get(fd).List()
+		var vs []any
+		for i := 0; i < l.Len(); i += 1 {
+			v, err := resolveValue(fd, l.Get(i), rest, value)
+			if err != nil {
+				return nil, err
+			}
+			if v != nil {
+				vs = append(vs, v)
+			}
+		}
+		return vs, nil
+	case fd.IsMap():
+		if len(rest) == 0 {
+			return nil, fmt.Errorf("map field %s needs a key", fd.Name())
+		}
+		k, err := mapKey(fd.MapKey(), rest[0])
+		if err != nil {
+			return nil, err
+		}
+		v := m.Get(fd).Map().Get(k)
+		if !v.IsValid() {
+			return nil, nil
+		}
+		return resolveValue(fd.MapValue(), v, rest[1:], value)
+	case fd.Message() != nil && !m.Has(fd):
+		return nil, nil
+	}
+	return resolveValue(fd, m.Get(fd), rest, value)
+}
+
+// resolveValue converts a (singular) field value, resolving the rest of the
+// path for messages.
+func resolveValue(fd protoreflect.FieldDescriptor, v protoreflect.Value, rest []string, value string) (any, error) {
+	if md := fd.Message(); md != nil {
+		switch md.FullName() {
+		case "google.protobuf.Timestamp":
+			if len(rest) == 0 {
+				s, n := secondsNanos(v.Message())
+				return time.Unix(s, n).UTC(), nil
+			}
+		case "google.protobuf.Duration":
+			if len(rest) == 0 {
+				s, n := secondsNanos(v.Message())
+				return time.Duration(s)*time.Second + time.Duration(n), nil
+			}
+		}
+		if len(rest) == 0 {
+			return nil, fmt.Errorf("cannot compare message field %s", fd.Name())
+		}
+		return resolve(v.Message(), rest, value)
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("field %s is not a message", fd.Name())
+	}
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		n := v.Enum()
+		if _, err := strconv.ParseInt(value, 10, 32); err == nil {
+			return int64(n), nil
+		}
+		if ev := fd.Enum().Values().ByNumber(n); ev != nil {
+			return string(ev.Name()), nil
+		}
+		return strconv.Itoa(int(n)), nil
+	case protoreflect.BytesKind:
+		return nil, fmt.Errorf("cannot compare bytes field %s", fd.Name())
+	}
+	return v.Interface(), nil
+}
+
+// field looks up a field by its proto name or, failing that, its JSON name.
+func field(md protoreflect.MessageDescriptor, name string) (protoreflect.FieldDescriptor, error) {
+	fields := md.Fields()
+	if fd := fields.ByName(protoreflect.Name(name)); fd != nil {
+		return fd, nil
+	}
+	if fd := fields.ByJSONName(name); fd != nil {
+		return fd, nil
+	}
+	names := make([]string, fields.Len())
+	for i := range names {
+		names[i] = string(fields.Get(i).Name())
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown field %q in %s, expected one of: %s", name, md.FullName(), strings.Join(names, ", "))
+}
+
+// mapKey converts a key part to a key for the map field.
+func mapKey(fd protoreflect.FieldDescriptor, s string) (protoreflect.MapKey, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(s).MapKey(), nil
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return protoreflect.MapKey{}, fmt.Errorf("invalid map key %q: %v", s, err)
+		}
+		return protoreflect.ValueOfBool(b).MapKey(), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		i, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return protoreflect.MapKey{}, fmt.Errorf("invalid map key %q: %v", s, err)
+		}
+		return protoreflect.ValueOfInt32(int32(i)).MapKey(), nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return protoreflect.MapKey{}, fmt.Errorf("invalid map key %q: %v", s, err)
+		}
+		return protoreflect.ValueOfInt64(i).MapKey(), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		i, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return protoreflect.MapKey{}, fmt.Errorf("invalid map key %q: %v", s, err)
+		}
+		return protoreflect.ValueOfUint32(uint32(i)).MapKey(), nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		i, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return protoreflect.MapKey{}, fmt.Errorf("invalid map key %q: %v", s, err)
+		}
+		return protoreflect.ValueOfUint64(i).MapKey(), nil
+	}
+	return protoreflect.MapKey{}, fmt.Errorf("unsupported map key type %s", fd.Kind())
+}
+
+// secondsNanos returns the seconds and nanos fields of a Timestamp or
+// Duration message.
+func secondsNanos(m protoreflect.Message) (int64, int64) {
+	fields := m.Descriptor().Fields()
+	s := m.Get(fields.ByName("seconds")).Int()
+	n := m.Get(fields.ByName("nanos")).Int()
+	return s, n
+}
@@ -0,0 +1,150 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package protomatch
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HayoVanLoon/go-listfilter"
+	"github.com/HayoVanLoon/go-listfilter/protomatch/internal/testpb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestMatchProto(t *testing.T) {
+	msg := &testpb.Resource{
+		DisplayName: "foo",
+		Size:        10,
+		Score:       1.5,
+		Active:      true,
+		State:       testpb.State_ACTIVE,
+		CreateTime:  timestamppb.New(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)),
+		Ttl:         durationpb.New(90 * time.Second),
+		Owner:       &testpb.Owner{Id: 42, Name: "joe"},
+		Tags:        []string{"a", "b"},
+		Items:       []*testpb.Item{{Sku: "abc", Qty: 1}, {Sku: "def", Qty: 5}},
+		Labels:      map[string]string{"env": "prod"},
+		Data:        []byte("data"),
+	}
+	p := listfilter.NewParser(listfilter.OptionOperators("<", ">", "<=", ">="))
+	tests := []struct {
+		name    string
+		query   string
+		opts    []listfilter.Option
+		want    bool
+		wantErr bool
+	}{
+		{"string", "display_name=foo", nil, true, false},
+		{"json name", "displayName=foo", nil, true, false},
+		{"snake case option", "displayName=foo", []listfilter.Option{listfilter.OptionSnakeCase()}, true, false},
+		{"int", "size>=10", nil, true, false},
+		{"double", "score<2", nil, true, false},
+		{"bool", "active=true", nil, true, false},
+		{"zero value", "previous_owner.id=0", nil, false, false},
+		{"enum by name", "state=ACTIVE", nil, true, false},
+		{"enum by name, no match", "state=DELETED", nil, false, false},
+		{"enum by number", "state=1", nil, true, false},
+		{"enum by number, ordering", "state<2", nil, true, false},
+		{"timestamp", "create_time>2024-01-01", nil, true, false},
+		{"timestamp, equal", "create_time=2024-03-01T13:00:00+01:00", nil, true, false},
+		{"duration", "ttl>1m", nil, true, false},
+		{"duration, equal", "ttl=90s", nil, true, false},
+		{"nested", "owner.name=joe", nil, true, false},
+		{"unset message", "previous_owner.name=joe", nil, false, false},
+		{"repeated", "tags=b", nil, true, false},
+		{"repeated, no match", "tags=c", nil, false, false},
+		{"repeated, not equal", "tags!=c", nil, true, false},
+		{"repeated, not equal, no match", "tags!=a", nil, false, false},
+		{"repeated messages, not equal", "items.sku!=abc", nil, false, false},
+		{"repeated messages", "items.sku=def", nil, true, false},
+		{"repeated messages, ordering", "items.qty>3", nil, true, false},
+		{"map", "labels.env=prod", nil, true, false},
+		{"map, missing key", "labels.team=core", nil, false, false},
+		{"and, or", "display_name=bar OR size>5 AND tags=a", nil, true, false},
+		{"! unknown field", "colour=red", nil, false, true},
+		{"! unknown nested field", "owner.email=joe", nil, false, true},
+		{"! message", "owner=joe", nil, false, true},
+		{"! map without key", "labels=prod", nil, false, true},
+		{"! path through scalar", "size.x=1", nil, false, true},
+		{"! bytes", "data=data", nil, false, true},
+		{"! mismatch", "size=abc", nil, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := p
+			if tt.opts != nil {
+				p = listfilter.NewParser(tt.opts...)
+			}
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got, err := MatchProto(msg, f)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MatchProto() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("MatchProto() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchProto_unknownField(t *testing.T) {
+	f, err := listfilter.NewParser().Parse("owner.email=joe")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	_, err = MatchProto(&testpb.Resource{Owner: &testpb.Owner{}}, f)
+	if err == nil {
+		t.Fatal("MatchProto() expected error")
+	}
+	if want := "expected one of: id, name"; !strings.Contains(err.Error(), want) {
+		t.Errorf("MatchProto() error = %v, want it to contain %q", err, want)
+	}
+}
+
+func TestMatchProto_missingField(t *testing.T) {
+	msg := &testpb.Resource{DisplayName: "foo", Labels: map[string]string{"env": "prod"}}
+	tests := []struct {
+		query        string
+		wantNoMatch  bool
+		wantNotEqual bool
+		wantErr      bool
+	}{
+		{"display_name=foo", true, true, false},
+		{"owner.name=joe", false, false, true},
+		{"owner.name!=joe", false, true, true},
+		{"labels.team=core", false, false, true},
+		{"labels.team!=core", false, true, true},
+		{"tags!=a", false, true, true},
+		{"display_name=foo OR owner.name=joe", true, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			f, err := listfilter.NewParser().Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			policies := []struct {
+				policy  listfilter.MissingFieldPolicy
+				want    bool
+				wantErr bool
+			}{
+				{listfilter.MissingFieldNoMatch, tt.wantNoMatch, false},
+				{listfilter.MissingFieldMatchNotEqual, tt.wantNotEqual, false},
+				{listfilter.MissingFieldError, tt.wantNoMatch && !tt.wantErr, tt.wantErr},
+			}
+			for _, pp := range policies {
+				got, err := MatchProto(msg, f, listfilter.MatchOptionMissingField(pp.policy))
+				if (err != nil) != pp.wantErr || got != pp.want {
+					t.Errorf("MatchProto() with policy %d got = %v, %v, want %v", pp.policy, got, err, pp.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// IsSatisfiable reports whether the filter could match anything. The analysis
+// is conservative: it only reports false for obvious contradictions, like
+// 'status=open AND status=closed' or 'age>10 AND age<5'.
+//
+// As OR binds more tightly than AND (see the package documentation), the
+// conditions of OR groups with a single condition must hold together. They
+// contradict each other if, for the same key, they require different values,
+// require and forbid the same value, or, when all values involved are
+// numbers, describe an empty numeric range. An OR group with more conditions is
+// satisfiable if any of its conditions is consistent with the former. Only
+// the operators '=', '!=', '<', '<=', '>' and '>=' are taken into account.
+// Fields are assumed not to be repeated; see IsSatisfiableWith.
+//
+// The error is currently always nil.
+func (f filter) IsSatisfiable() (bool, error) {
+	return IsSatisfiableWith(f)
+}
+
+// A SatisfiableOption can be passed to IsSatisfiableWith.
+type SatisfiableOption interface {
+	Apply(cfg *satisfiableConfig)
+}
+
+type satisfiableConfig struct {
+	repeated    map[string]bool
+	allRepeated bool
+}
+
+type satisfiableOptionRepeated []string
+
+func (o satisfiableOptionRepeated) Apply(cfg *satisfiableConfig) {
+	if len(o) == 0 {
+		cfg.allRepeated = true
+	}
+	for _, k := range o {
+		cfg.repeated[k] = true
+	}
+}
+
+// SatisfiableOptionRepeated declares that the fields for the keys may be
+// repeated, or, without keys, that any field may be. Fields below a repeated
+// field are repeated as well. As a condition on a
+// repeated field holds if it holds for any of its elements (see
+// Filter.MatchDocument), conditions on these keys only contradict each other
+// if they require and forbid the same value. For instance, 'tags=a AND
+// tags=b' is satisfiable if tags is repeated.
+func SatisfiableOptionRepeated(keys ...string) SatisfiableOption {
+	return satisfiableOptionRepeated(keys)
+}
+
+// IsSatisfiableWith is like Filter.IsSatisfiable, with options.
+func IsSatisfiableWith(f Filter, opts ...SatisfiableOption) (bool, error) {
+	cfg := &satisfiableConfig{repeated: make(map[string]bool)}
+	for _, opt := range opts {
+		opt.Apply(cfg)
+	}
+	var units []Condition
+	var groups [][]Condition
+	for _, g := range orGroups(f) {
+		if len(g) == 1 {
+			units = append(units, g[0])
+		} else {
+			groups = append(groups, g)
+		}
+	}
+	if !cfg.consistent(units) {
+		return false, nil
+	}
+	for _, g := range groups {
+		ok := false
+		for _, c := range g {
+			if cfg.consistent(append(units[:len(units):len(units)], c)) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// consistent reports whether the conditions could all hold at the same time.
+func (cfg *satisfiableConfig) consistent(cs []Condition) bool {
+	byKey := make(map[string][]Condition)
+	for _, c := range cs {
+		byKey[c.Key()] = append(byKey[c.Key()], c)
+	}
+	for _, cs := range byKey {
+		if !consistentKey(cs, !cfg.isRepeated(cs[0].KeyParts())) {
+			return false
+		}
+	}
+	return true
+}
+
+// isRepeated reports whether the field for the key, or one above it, may be
+// repeated.
+func (cfg *satisfiableConfig) isRepeated(parts []string) bool {
+	if cfg.allRepeated {
+		return true
+	}
+	for i := range parts {
+		if cfg.repeated[strings.Join(parts[:i+1], string(nameSeparator))] {
+			return true
+		}
+	}
+	return false
+}
+
+// consistentKey reports whether the conditions on a single key could all hold
+// at the same time. Unless the key is scalar, only a value that is both
+// required and forbidden is a contradiction.
+func consistentKey(cs []Condition, scalar bool) bool {
+	var eqs, nes []string
+	lo, hi := math.Inf(-1), math.Inf(1)
+	loStrict, hiStrict := false, false
+	for _, c := range cs {
+		switch c.Op() {
+		case "=":
+			eqs = append(eqs, c.StringValue())
+			continue
+		case "!=":
+			nes = append(nes, c.StringValue())
+			continue
+		}
+		x, ok := number(c.StringValue())
+		if !ok {
+			continue
+		}
+		switch c.Op() {
+		case ">", ">=":
+			strict := c.Op() == ">"
+			if x > lo || (x == lo && strict) {
+				lo, loStrict = x, strict
+			}
+		case "<", "<=":
+			strict := c.Op() == "<"
+			if x < hi || (x == hi && strict) {
+				hi, hiStrict = x, strict
+			}
+		}
+	}
+	for _, e := range eqs {
+		for _, n := range nes {
+			if e == n {
+				return false
+			}
+		}
+	}
+	if !scalar {
+		return true
+	}
+	if lo > hi || (lo == hi && (loStrict || hiStrict)) {
+		return false
+	}
+	for i, e := range eqs {
+		for _, other := range eqs[i+1:] {
+			if differentValues(e, other) {
+				return false
+			}
+		}
+		if x, ok := number(e); ok {
+			if x < lo || (x == lo && loStrict) || x > hi || (x == hi && hiStrict) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// differentValues reports whether the values required by '=' conditions
+// certainly differ. Values that represent the same number, like '1' and '1.0',
+// might not. Nor might a glob pattern and a value it matches, or two
+// patterns.
+func differentValues(a, b string) bool {
+	if a == b {
+		return false
+	}
+	ta, globA := parseGlob(a)
+	tb, globB := parseGlob(b)
+	switch {
+	case globA && globB:
+		return false
+	case globA:
+		return !matchGlob(ta, b, false)
+	case globB:
+		return !matchGlob(tb, a, false)
+	}
+	x, okA := number(a)
+	y, okB := number(b)
+	return !okA || !okB || x != y
+}
+
+// number returns the value as a number, if it is one.
+func number(s string) (float64, bool) {
+	x, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(x) {
+		return 0, false
+	}
+	return x, true
+}
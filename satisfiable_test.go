@@ -0,0 +1,150 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"testing"
+)
+
+func TestFilter_IsSatisfiable(t *testing.T) {
+	p := NewParser(OptionOperators("<", "<=", ">", ">=", ":"))
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", true},
+		{"status=open", true},
+		{"status=open AND status=open", true},
+		{"status=open AND status=closed", false},
+		{"status=open AND status!=open", false},
+		{"status=open AND status!=closed", true},
+		{"status!=open AND status!=closed", true},
+		{"status=open AND state=closed", true},
+		{"age>10 AND age<5", false},
+		{"age>10 AND age<20", true},
+		{"age>10 AND age<=10", false},
+		{"age>=10 AND age<=10", true},
+		{"age>=10 AND age<10", false},
+		{"age=1 AND age=1.0", true},
+		{"age=1 AND age=2", false},
+		{"age=5 AND age>10", false},
+		{"age=10 AND age>=10", true},
+		{"age=10 AND age>10", false},
+		{"age=10 AND age!=10.0", true},
+		{"age>abc AND age<5", true},
+		{"name:foo AND name:bar", true},
+		{"status=closed OR status=open AND status=open", true},
+		{"status=closed OR status=pending AND status=open", false},
+		{"age<5 OR age>20 AND age>10", true},
+		{"age<5 OR age<8 AND age>10", false},
+		{"a=1 OR b=2 AND a=2 OR b=1", true},
+		{"name=ba* AND name=bar", true},
+		{"name=bar AND name=b?r", true},
+		{"name=ba* AND name=foo", false},
+		{"name=ba* AND name=*r", true},
+		{`name=ba\* AND name=bar`, false},
+		{`name=ba\* AND name=ba*`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got, err := f.IsSatisfiable()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsSatisfiable() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsSatisfiableWith(t *testing.T) {
+	p := NewParser(OptionOperators("<", "<=", ">", ">=", ":"))
+	tests := []struct {
+		query string
+		opts  []SatisfiableOption
+		want  bool
+	}{
+		{"tags=a AND tags=b", nil, false},
+		{"tags=a AND tags=b", []SatisfiableOption{SatisfiableOptionRepeated("tags")}, true},
+		{"tags=a AND tags=b", []SatisfiableOption{SatisfiableOptionRepeated()}, true},
+		{"tags=a AND tags=b", []SatisfiableOption{SatisfiableOptionRepeated("other")}, false},
+		{"tags>10 AND tags<5", []SatisfiableOption{SatisfiableOptionRepeated("tags")}, true},
+		{"tags=a AND tags!=a", []SatisfiableOption{SatisfiableOptionRepeated("tags")}, false},
+		{"tags=b OR tags=a AND tags!=a", []SatisfiableOption{SatisfiableOptionRepeated("tags")}, true},
+		{"tags=a OR tags=a AND tags!=a", []SatisfiableOption{SatisfiableOptionRepeated("tags")}, false},
+		{"tags=a AND tags=b AND status=open AND status=closed", []SatisfiableOption{SatisfiableOptionRepeated("tags")}, false},
+		{"items.sku=a AND items.sku=b", []SatisfiableOption{SatisfiableOptionRepeated("items")}, true},
+		{"items.sku=a AND items.sku=b", []SatisfiableOption{SatisfiableOptionRepeated("items.sku")}, true},
+		{"items.sku=a AND items.sku=b", []SatisfiableOption{SatisfiableOptionRepeated("items.sk")}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got, err := IsSatisfiableWith(f, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsSatisfiableWith() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestIsSatisfiableWith_matchDocument checks that filters matching a
+// document are not reported as unsatisfiable.
+func TestIsSatisfiableWith_matchDocument(t *testing.T) {
+	p := NewParser(OptionOperators("<", "<=", ">", ">=", ":"))
+	tests := []struct {
+		query string
+		doc   map[string]any
+	}{
+		{"tags=a AND tags=b", map[string]any{"tags": []any{"a", "b"}}},
+		{"age>10 AND age<5", map[string]any{"age": []any{12, 3}}},
+		{"age=5 AND age>10", map[string]any{"age": []any{5, 11}}},
+		{"age=1 AND age=2 AND age!=3", map[string]any{"age": []any{1, 2}}},
+		{"a.b=1 AND a.b=2", map[string]any{"a": []any{map[string]any{"b": 1}, map[string]any{"b": 2}}}},
+		{"status=open AND age>10 AND age<20", map[string]any{"status": "open", "age": 15}},
+		{"name=ba* AND name=bar", map[string]any{"name": "bar"}},
+		{`name=ba\* AND name=ba*`, map[string]any{"name": "ba*"}},
+		{"status=open OR status=closed AND status!=closed", map[string]any{"status": "open"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			if ok, err := f.MatchDocument(tt.doc); !ok || err != nil {
+				t.Fatalf("MatchDocument() got = %v, %v, want true", ok, err)
+			}
+			var repeated []string
+			for k, v := range tt.doc {
+				if _, ok := v.([]any); ok {
+					repeated = append(repeated, k)
+				}
+			}
+			var opts []SatisfiableOption
+			if repeated != nil {
+				opts = append(opts, SatisfiableOptionRepeated(repeated...))
+			}
+			got, err := IsSatisfiableWith(f, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got {
+				t.Errorf("IsSatisfiableWith(%v) got = false for filter matching %v", repeated, tt.doc)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A Version is a semantic version (https://semver.org). Build metadata is
+// not retained, as it has no bearing on version precedence.
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+}
+
+// ParseVersion parses a semantic version string. An optional leading 'v' is
+// accepted.
+func ParseVersion(s string) (Version, error) {
+	v := strings.TrimPrefix(s, "v")
+	if i := strings.IndexByte(v, '+'); i >= 0 {
+		if !validIdentifiers(v[i+1:], false) {
+			return Version{}, fmt.Errorf("%s is not a valid version", truncate(s))
+		}
+		v = v[:i]
+	}
+	var pre string
+	if i := strings.IndexByte(v, '-'); i >= 0 {
+		v, pre = v[:i], v[i+1:]
+		if !validIdentifiers(pre, true) {
+			return Version{}, fmt.Errorf("%s is not a valid version", truncate(s))
+		}
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("%s is not a valid version", truncate(s))
+	}
+	var nums [3]int
+	for i, p := range parts {
+		n, ok := versionNumber(p)
+		if !ok {
+			return Version{}, fmt.Errorf("%s is not a valid version", truncate(s))
+		}
+		nums[i] = n
+	}
+	return Version{nums[0], nums[1], nums[2], pre}, nil
+}
+
+// versionNumber parses a numeric version identifier, which must not have
+// leading zeroes.
+func versionNumber(s string) (int, bool) {
+	if s == "" || len(s) > 1 && s[0] == '0' {
+		return 0, false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
+
+// validIdentifiers checks a dot-separated list of pre-release or build
+// identifiers. Numeric pre-release identifiers must not have leading zeroes.
+func validIdentifiers(s string, prerelease bool) bool {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return false
+		}
+		numeric := true
+		for _, r := range id {
+			switch {
+			case r >= '0' && r <= '9':
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '-':
+				numeric = false
+			default:
+				return false
+			}
+		}
+		if prerelease && numeric && len(id) > 1 && id[0] == '0' {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare returns -1, 0 or 1 when the version has a lower, equal or higher
+// precedence than other.
+func (v Version) Compare(other Version) int {
+	if c := compareInts(v.Major, other.Major); c != 0 {
+		return c
+	}
+	if c := compareInts(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+	if c := compareInts(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+	return comparePrereleases(v.Prerelease, other.Prerelease)
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// comparePrereleases compares pre-release versions. A version without a
+// pre-release has a higher precedence than one with.
+func comparePrereleases(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i += 1 {
+		if c := compareIdentifiers(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(as), len(bs))
+}
+
+// compareIdentifiers compares numeric identifiers numerically and others
+// lexically. Numeric identifiers have a lower precedence than others.
+func compareIdentifiers(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareInts(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,101 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    Version
+		wantErr bool
+	}{
+		{"simple", "1.2.3", Version{1, 2, 3, ""}, false},
+		{"leading v", "v1.10.0", Version{1, 10, 0, ""}, false},
+		{"pre-release", "1.0.0-rc.1", Version{1, 0, 0, "rc.1"}, false},
+		{"build metadata", "1.0.0-alpha+001", Version{1, 0, 0, "alpha"}, false},
+		{"zeroes", "0.0.0", Version{0, 0, 0, ""}, false},
+		{"! empty", "", Version{}, true},
+		{"! two parts", "1.2", Version{}, true},
+		{"! four parts", "1.2.3.4", Version{}, true},
+		{"! leading zero", "01.2.3", Version{}, true},
+		{"! letters", "1.x.3", Version{}, true},
+		{"! empty pre-release", "1.2.3-", Version{}, true},
+		{"! empty pre-release identifier", "1.2.3-rc..1", Version{}, true},
+		{"! leading zero pre-release", "1.2.3-rc.01", Version{}, true},
+		{"! invalid build", "1.2.3+a_b", Version{}, true},
+		{"! double v", "vv1.2.3", Version{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseVersion(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseVersion() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseVersion() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_condition_CompareVersion(t *testing.T) {
+	tests := []struct {
+		value   string
+		other   string
+		want    int
+		wantErr bool
+	}{
+		{"1.2.0", "1.2.0", 0, false},
+		{"1.10.0", "1.2.0", 1, false},
+		{"1.2.0", "1.10.0", -1, false},
+		{"v2.0.0", "1.99.99", 1, false},
+		{"1.0.1", "1.0.0", 1, false},
+		{"1.0.0-rc.1", "1.0.0", -1, false},
+		{"1.0.0", "1.0.0-rc.1", 1, false},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1, false},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1, false},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1, false},
+		{"1.0.0-beta", "1.0.0-alpha", 1, false},
+		{"1.0.0+build.1", "1.0.0+build.2", 0, false},
+		{"1.0", "1.0.0", 0, true},
+		{"1.0.0", "latest", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value+" vs "+tt.other, func(t *testing.T) {
+			c := NewCondition("version", []string{"version"}, ">=", tt.value)
+			got, err := c.CompareVersion(tt.other)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CompareVersion() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("CompareVersion() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_condition_SemverValue(t *testing.T) {
+	c := NewCondition("version", []string{"version"}, ">=", "v1.2.3-rc.1")
+	got, err := c.SemverValue()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (Version{1, 2, 3, "rc.1"}); got != want {
+		t.Errorf("SemverValue() got = %v, want %v", got, want)
+	}
+	if got.String() != "1.2.3-rc.1" {
+		t.Errorf("String() got = %v", got.String())
+	}
+	c = NewCondition("version", []string{"version"}, ">=", "foo")
+	if _, err = c.SemverValue(); err == nil {
+		t.Errorf("expected error")
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// An SQLOption can be passed to ToSQL.
+type SQLOption interface {
+	Apply(cfg *sqlConfig)
+}
+
+type sqlConfig struct {
+	// joiner joins key parts into a single column name, empty means each part
+	// is a separate identifier
+	joiner  string
+	dialect SQLDialect
+	mapping func(key string) (string, bool)
+	strict  bool
+}
+
+// newSQLConfig creates a configuration from the options.
+func newSQLConfig(opts []SQLOption) *sqlConfig {
+	cfg := &sqlConfig{}
+	for _, opt := range opts {
+		opt.Apply(cfg)
+	}
+	return cfg
+}
+
+type sqlOptionKeyJoiner string
+
+func (o sqlOptionKeyJoiner) Apply(cfg *sqlConfig) {
+	cfg.joiner = string(o)
+}
+
+// SQLOptionKeyJoiner will make ToSQL join the parts of dotted keys with sep
+// into a single column name, like "owner_id" for 'owner.id' and sep "_".
+func SQLOptionKeyJoiner(sep string) SQLOption {
+	return sqlOptionKeyJoiner(sep)
+}
+
+// An SQLDialect determines the placeholder style and identifier quoting of
+// the generated SQL.
+type SQLDialect int
+
+const (
+	// SQLDialectDefault uses '?' placeholders and double-quoted identifiers.
+	SQLDialectDefault SQLDialect = iota
+	// SQLDialectPostgres uses numbered placeholders ($1, $2, ...) and
+	// double-quoted identifiers.
+	SQLDialectPostgres
+	// SQLDialectMySQL uses '?' placeholders and backtick-quoted identifiers.
+	SQLDialectMySQL
+	// SQLDialectSQLite uses '?' placeholders and double-quoted identifiers.
+	SQLDialectSQLite
+
+	// sqlDialectGoogle is used by ToGoogleSQL.
+	sqlDialectGoogle
+)
+
+// placeholder returns the placeholder for the n-th value, counting from 1.
+func (d SQLDialect) placeholder(n int) string {
+	if d == SQLDialectPostgres {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// quote quotes s as an identifier.
+func (d SQLDialect) quote(s string) string {
+	switch d {
+	case SQLDialectMySQL:
+		return "`" + strings.ReplaceAll(s, "`", "``") + "`"
+	case sqlDialectGoogle:
+		return "`" + googleSQLEscaper.Replace(s) + "`"
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+type sqlOptionDialect SQLDialect
+
+func (o sqlOptionDialect) Apply(cfg *sqlConfig) {
+	cfg.dialect = SQLDialect(o)
+}
+
+// SQLOptionDialect sets the SQL dialect. The default is SQLDialectDefault.
+func SQLOptionDialect(d SQLDialect) SQLOption {
+	return sqlOptionDialect(d)
+}
+
+type sqlOptionColumnFunc func(key string) (string, bool)
+
+func (o sqlOptionColumnFunc) Apply(cfg *sqlConfig) {
+	cfg.mapping = o
+}
+
+// SQLOptionColumnFunc will make ToSQL use fn to map condition keys to column
+// names. A column name can be qualified, like 'users.created_at'; its parts
+// are quoted separately. Keys for which fn returns false result in an error,
+// so only mapped columns can be filtered on.
+func SQLOptionColumnFunc(fn func(key string) (string, bool)) SQLOption {
+	return sqlOptionColumnFunc(fn)
+}
+
+// SQLOptionColumns will make ToSQL map condition keys to the column names in
+// m, like SQLOptionColumnFunc.
+func SQLOptionColumns(m map[string]string) SQLOption {
+	return sqlOptionColumnFunc(func(key string) (string, bool) {
+		col, ok := m[key]
+		return col, ok
+	})
+}
+
+type sqlOptionStrict struct{}
+
+func (o sqlOptionStrict) Apply(cfg *sqlConfig) {
+	cfg.strict = true
+}
+
+// SQLOptionStrict will make ToSQL return an error for keys that have no
+// column mapping (see SQLOptionColumns), which prevents filtering on columns
+// that are not meant to be exposed. Unmapped keys are always rejected when a
+// mapping is used; with this option, they are also rejected without one.
+func SQLOptionStrict() SQLOption {
+	return &sqlOptionStrict{}
+}
+
+// sqlOperators maps filter operators to their SQL equivalent.
+var sqlOperators = map[string]string{
+	"=": "=", "!=": "<>", "<": "<", ">": ">", "<=": "<=", ">=": ">=",
+}
+
+// ToSQL translates the filter into the condition of an SQL WHERE clause, with
+// a placeholder for every value (see SQLDialect). The values are returned as
+// args, in order, as strings. Keys can be mapped to column names with
+// SQLOptionColumns, in which case keys without a mapping result in an
+// error. Otherwise, the parts of dotted keys are quoted as separate
+// identifiers, like "table"."column", unless SQLOptionKeyJoiner is used. OR
+// groups with more than one condition are parenthesised. The operators '=',
+// '!=', '<', '>', '<=' and '>=' are supported; for others, an error is
+// returned. An empty filter results in an empty clause.
+func ToSQL(f Filter, opts ...SQLOption) (clause string, args []any, err error) {
+	cfg := newSQLConfig(opts)
+	clause, err = cfg.render(f, func(c Condition) string {
+		args = append(args, c.StringValue())
+		return cfg.dialect.placeholder(len(args))
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return clause, args, nil
+}
+
+// render renders the filter as an SQL condition, using placeholder to get the
+// placeholder for each condition's value.
+func (cfg *sqlConfig) render(f Filter, placeholder func(c Condition) string) (string, error) {
+	var ands []string
+	for _, g := range orGroups(f) {
+		var ors []string
+		for _, c := range g {
+			op, ok := sqlOperators[c.Op()]
+			if !ok {
+				return "", fmt.Errorf("%s: unsupported operator %s", c.Key(), c.Op())
+			}
+			col, err := cfg.column(c)
+			if err != nil {
+				return "", err
+			}
+			ors = append(ors, col+" "+op+" "+placeholder(c))
+		}
+		s := strings.Join(ors, " OR ")
+		if len(ors) > 1 {
+			s = "(" + s + ")"
+		}
+		ands = append(ands, s)
+	}
+	return strings.Join(ands, " AND "), nil
+}
+
+// column renders the condition's key as a column reference.
+func (cfg *sqlConfig) column(c Condition) (string, error) {
+	if cfg.mapping != nil {
+		if col, ok := cfg.mapping(c.Key()); ok {
+			return cfg.identifiers(strings.Split(col, ".")), nil
+		}
+	}
+	if cfg.strict || cfg.mapping != nil {
+		return "", fmt.Errorf("%s: unknown key", c.Key())
+	}
+	if cfg.joiner != "" {
+		return cfg.dialect.quote(strings.Join(c.KeyParts(), cfg.joiner)), nil
+	}
+	return cfg.identifiers(c.KeyParts()), nil
+}
+
+// identifiers quotes the parts separately and joins them with dots.
+func (cfg *sqlConfig) identifiers(parts []string) string {
+	qs := make([]string, len(parts))
+	for i, p := range parts {
+		qs[i] = cfg.dialect.quote(p)
+	}
+	return strings.Join(qs, ".")
+}
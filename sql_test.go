@@ -0,0 +1,172 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestToSQL(t *testing.T) {
+	p := NewParser(OptionOperators("<", ">", "<=", ">=", ":"))
+	tests := []struct {
+		name     string
+		query    string
+		opts     []SQLOption
+		want     string
+		wantArgs []any
+		wantErr  bool
+	}{
+		{"empty", "", nil, "", nil, false},
+		{"equal", "status=open", nil, `"status" = ?`, []any{"open"}, false},
+		{"operators",
+			"a=1 AND b!=2 AND c<3 AND d>4 AND e<=5 AND f>=6",
+			nil,
+			`"a" = ? AND "b" <> ? AND "c" < ? AND "d" > ? AND "e" <= ? AND "f" >= ?`,
+			[]any{"1", "2", "3", "4", "5", "6"},
+			false},
+		{"or", "status=open OR status=pending", nil, `("status" = ? OR "status" = ?)`, []any{"open", "pending"}, false},
+		{"or binds tighter",
+			"status=open OR status=pending AND size>10",
+			nil,
+			`("status" = ? OR "status" = ?) AND "size" > ?`,
+			[]any{"open", "pending", "10"},
+			false},
+		{"or groups",
+			"a=1 AND b=2 OR c=3 AND d=4",
+			nil,
+			`"a" = ? AND ("b" = ? OR "c" = ?) AND "d" = ?`,
+			[]any{"1", "2", "3", "4"},
+			false},
+		{"dotted key", "owner.id=42", nil, `"owner"."id" = ?`, []any{"42"}, false},
+		{"dotted key, joiner", "owner.id=42", []SQLOption{SQLOptionKeyJoiner("_")}, `"owner_id" = ?`, []any{"42"}, false},
+		{"quoted value", `name="Robert'); DROP TABLE students;--"`, nil, `"name" = ?`, []any{"Robert'); DROP TABLE students;--"}, false},
+		{"quoted value with quotes", `title="say \"hi\""`, nil, `"title" = ?`, []any{`say "hi"`}, false},
+		{"! unsupported operator", "tags:urgent", nil, "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			got, args, err := ToSQL(f, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToSQL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ToSQL() got = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("ToSQL() args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestToSQL_dialects(t *testing.T) {
+	f, err := NewParser(OptionOperators(">")).Parse("status=open OR status=pending AND owner.id>42")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	tests := []struct {
+		name    string
+		dialect SQLDialect
+		want    string
+	}{
+		{"default", SQLDialectDefault, `("status" = ? OR "status" = ?) AND "owner"."id" > ?`},
+		{"postgres", SQLDialectPostgres, `("status" = $1 OR "status" = $2) AND "owner"."id" > $3`},
+		{"mysql", SQLDialectMySQL, "(`status` = ? OR `status` = ?) AND `owner`.`id` > ?"},
+		{"sqlite", SQLDialectSQLite, `("status" = ? OR "status" = ?) AND "owner"."id" > ?`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args, err := ToSQL(f, SQLOptionDialect(tt.dialect))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ToSQL() got = %v, want %v", got, tt.want)
+			}
+			if want := []any{"open", "pending", "42"}; !reflect.DeepEqual(args, want) {
+				t.Errorf("ToSQL() args = %v, want %v", args, want)
+			}
+		})
+	}
+}
+
+func TestToSQL_columns(t *testing.T) {
+	p := NewParser(OptionOperators(">="))
+	columns := map[string]string{
+		"createdAt": "created_at",
+		"owner.id":  "owner_id",
+		"team":      "t.name",
+		"odd":       `we"ird`,
+		"odder":     "we`ird",
+	}
+	tests := []struct {
+		name    string
+		query   string
+		opts    []SQLOption
+		want    string
+		wantErr bool
+	}{
+		{"mapped", "createdAt>=2024-01-01", nil, `"created_at" >= ?`, false},
+		{"mapped, dotted key", "owner.id=42", nil, `"owner_id" = ?`, false},
+		{"mapped, qualified column", "team=core", nil, `"t"."name" = ?`, false},
+		{"mapped, quote", "odd=x", nil, `"we""ird" = ?`, false},
+		{"mapped, backtick", "odder=x", []SQLOption{SQLOptionDialect(SQLDialectMySQL)}, "`we``ird` = ?", false},
+		{"! unmapped", "secret=x", nil, "", true},
+		{"! unmapped, mapped first", "owner.id=42 AND secret=x", nil, "", true},
+		{"mapped, strict", "createdAt>=2024-01-01", []SQLOption{SQLOptionStrict()}, `"created_at" >= ?`, false},
+		{"! unmapped, strict", "owner.id=42 AND secret=x", []SQLOption{SQLOptionStrict()}, "", true},
+		{"mapped, dialect", "owner.id=42", []SQLOption{SQLOptionDialect(SQLDialectMySQL)}, "`owner_id` = ?", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			opts := append([]SQLOption{SQLOptionColumns(columns)}, tt.opts...)
+			got, _, err := ToSQL(f, opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToSQL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ToSQL() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToSQL_unmappedKey(t *testing.T) {
+	key := `id" = 1 OR "x`
+	f, err := NewFilterFromConditions(separatorAnd, NewCondition(key, []string{key}, "=", "42"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, _, err := ToSQL(f, SQLOptionColumns(map[string]string{"id": "id"}))
+	if err == nil {
+		t.Errorf("ToSQL() expected error, got %v", got)
+	}
+	got, _, err = ToSQL(f)
+	if err != nil {
+		t.Fatalf("ToSQL() unexpected error: %v", err)
+	}
+	if want := `"id"" = 1 OR ""x" = ?`; got != want {
+		t.Errorf("ToSQL() got = %v, want %v", got, want)
+	}
+}
+
+func TestToSQL_strictWithoutMapping(t *testing.T) {
+	f, err := NewParser().Parse("status=open")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	if _, _, err := ToSQL(f, SQLOptionStrict()); err == nil {
+		t.Errorf("ToSQL() expected error")
+	}
+}
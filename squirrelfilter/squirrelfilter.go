@@ -0,0 +1,67 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+// Package squirrelfilter translates filters into squirrel.Sqlizer conditions.
+// It is a separate module, so that the listfilter package itself does not
+// depend on squirrel.
+package squirrelfilter
+
+import (
+	"fmt"
+
+	"github.com/HayoVanLoon/go-listfilter"
+	sq "github.com/Masterminds/squirrel"
+)
+
+// ToSqlizer translates the filter into a squirrel.And of its OR groups, which
+// can be passed to a builder's Where method or combined with other Sqlizers.
+// OR groups with more than one condition become a squirrel.Or. Keys are
+// mapped to column names with mapping; unmapped keys result in an error.
+// Values are converted with listfilter.TypedValue. The operators '=', '!=',
+// '<', '>', '<=' and '>=' are supported; for others, an error is returned.
+func ToSqlizer(f listfilter.Filter, mapping map[string]string) (sq.Sqlizer, error) {
+	and := sq.And{}
+	var or sq.Or
+	for _, c := range f.Conditions() {
+		s, err := leaf(c, mapping)
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, s)
+		if _, next := c.AndOr(); next != nil {
+			continue
+		}
+		if len(or) == 1 {
+			and = append(and, or[0])
+		} else {
+			and = append(and, or)
+		}
+		or = nil
+	}
+	return and, nil
+}
+
+// leaf translates a single condition.
+func leaf(c listfilter.Condition, mapping map[string]string) (sq.Sqlizer, error) {
+	col, ok := mapping[c.Key()]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown key", c.Key())
+	}
+	v := listfilter.TypedValue(c)
+	switch c.Op() {
+	case "=":
+		return sq.Eq{col: v}, nil
+	case "!=":
+		return sq.NotEq{col: v}, nil
+	case "<":
+		return sq.Lt{col: v}, nil
+	case ">":
+		return sq.Gt{col: v}, nil
+	case "<=":
+		return sq.LtOrEq{col: v}, nil
+	case ">=":
+		return sq.GtOrEq{col: v}, nil
+	}
+	return nil, fmt.Errorf("%s: unsupported operator %s", c.Key(), c.Op())
+}
@@ -0,0 +1,96 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package squirrelfilter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/HayoVanLoon/go-listfilter"
+	sq "github.com/Masterminds/squirrel"
+)
+
+func TestToSqlizer(t *testing.T) {
+	mapping := map[string]string{
+		"name":       "name",
+		"size":       "size",
+		"owner.name": "o.name",
+		"active":     "active",
+	}
+	p := listfilter.NewParser(listfilter.OptionOperators("<", ">", "<=", ">=", ":"))
+	tests := []struct {
+		name     string
+		query    string
+		wantSQL  string
+		wantArgs []any
+		wantErr  bool
+	}{
+		{"empty", "", "(1=1)", nil, false},
+		{"single", "name=foo", "(name = ?)", []any{"foo"}, false},
+		{
+			"and",
+			`name!="foo" AND size>=10 AND active=true`,
+			"(name <> ? AND size >= ? AND active = ?)",
+			[]any{"foo", int64(10), true},
+			false,
+		},
+		{"or", "size<1 OR size>10", "((size < ? OR size > ?))", []any{int64(1), int64(10)}, false},
+		{
+			"mixed",
+			"name=foo AND size<=1 OR owner.name=bar",
+			"(name = ? AND (size <= ? OR o.name = ?))",
+			[]any{"foo", int64(1), "bar"},
+			false,
+		},
+		{"unmapped key", "name=foo AND colour=red", "", nil, true},
+		{"unsupported operator", "name:foo", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error parsing %q: %v", tt.query, err)
+			}
+			s, err := ToSqlizer(f, mapping)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			sql, args, err := s.ToSql()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("expected %q, got %q", tt.wantSQL, sql)
+			}
+			if len(args) != len(tt.wantArgs) || (len(args) > 0 && !reflect.DeepEqual(args, tt.wantArgs)) {
+				t.Errorf("expected %v, got %v", tt.wantArgs, args)
+			}
+		})
+	}
+}
+
+func TestToSqlizer_compose(t *testing.T) {
+	f, err := listfilter.NewParser().Parse("name=foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s, err := ToSqlizer(f, map[string]string{"name": "name"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sql, args, err := sq.Select("*").From("things").Where(s).Where(sq.Eq{"deleted": false}).ToSql()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "SELECT * FROM things WHERE (name = ?) AND deleted = ?"; sql != want {
+		t.Errorf("expected %q, got %q", want, sql)
+	}
+	if want := []any{"foo", false}; !reflect.DeepEqual(args, want) {
+		t.Errorf("expected %v, got %v", want, args)
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+// Stats summarises the structure of a Filter.
+type Stats struct {
+	// Conditions is the number of conditions.
+	Conditions int
+	// Keys is the number of distinct keys.
+	Keys int
+	// Operators holds the number of conditions per operator.
+	Operators map[string]int
+	// MaxDepth is the largest number of key parts in any key.
+	MaxDepth int
+	// HasOr reports whether any conditions are linked with OR.
+	HasOr bool
+}
+
+// Stats returns a summary of the filter's structure.
+func (f filter) Stats() Stats {
+	s := Stats{Operators: make(map[string]int)}
+	keys := make(map[string]bool)
+	for c := f.first; c != nil; {
+		next, sep := c.next()
+		s.Conditions += 1
+		keys[c.key] = true
+		s.Operators[c.op] += 1
+		if len(c.keyParts) > s.MaxDepth {
+			s.MaxDepth = len(c.keyParts)
+		}
+		s.HasOr = s.HasOr || sep == separatorOr
+		c = next
+	}
+	s.Keys = len(keys)
+	return s
+}
@@ -0,0 +1,43 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilter_Stats(t *testing.T) {
+	p := NewParser(OptionOperators("<", ">="))
+	tests := []struct {
+		name  string
+		query string
+		want  Stats
+	}{
+		{"empty", "", Stats{0, 0, map[string]int{}, 0, false}},
+		{"single", "foo=bar", Stats{1, 1, map[string]int{"=": 1}, 1, false}},
+		{
+			"mixed",
+			"foo=bar AND a.b.c.d!=1 OR foo=boo AND bla<2 AND a.b>=3",
+			Stats{5, 4, map[string]int{"=": 2, "!=": 1, "<": 1, ">=": 1}, 4, true},
+		},
+		{
+			"duplicate keys",
+			"foo=1 AND foo=2 AND foo!=3",
+			Stats{3, 1, map[string]int{"=": 2, "!=": 1}, 1, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			if got := f.Stats(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Stats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
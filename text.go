@@ -0,0 +1,43 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+// MarshalText encodes the filter as its filter string.
+func (f filter) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// Text holds a Filter that is encoded as, and decoded from, a filter string.
+// It can be used as a field type in (configuration) structs that are decoded
+// by packages supporting encoding.TextUnmarshaler, like encoding/json.
+// Decoding uses a Parser that, besides '=' and '!=', recognises the operators
+// '<', '>', '<=', '>=' and ':' (see OptionOperators).
+type Text struct {
+	Filter Filter
+}
+
+// MarshalText encodes the filter as its filter string. A nil Filter is encoded
+// as an empty string.
+func (t Text) MarshalText() ([]byte, error) {
+	if t.Filter == nil {
+		return []byte{}, nil
+	}
+	return t.Filter.MarshalText()
+}
+
+// textOperators are the operators recognised when decoding a Text, besides the
+// default ones.
+var textOperators = []string{"<", ">", "<=", ">=", ":"}
+
+// UnmarshalText parses the filter string. If parsing fails, the ParseError is
+// returned.
+func (t *Text) UnmarshalText(text []byte) error {
+	f, err := NewParser(OptionOperators(textOperators...)).Parse(string(text))
+	if err != nil {
+		return err
+	}
+	t.Filter = f
+	return nil
+}
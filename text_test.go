@@ -0,0 +1,106 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestFilter_MarshalText(t *testing.T) {
+	f, _ := NewParser().Parse(`foo="a b"  AND bar=1`)
+	got, err := f.MarshalText()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `foo="a b" AND bar=1`; string(got) != want {
+		t.Errorf("MarshalText() = %s, want %s", got, want)
+	}
+}
+
+func TestText_json(t *testing.T) {
+	type config struct {
+		Name   string `json:"name"`
+		Filter Text   `json:"filter"`
+	}
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{"simple", `{"name":"x","filter":"foo=bar AND bla=\"x y\""}`, `foo=bar AND bla="x y"`, false},
+		{"empty", `{"name":"x","filter":""}`, "", false},
+		{"ordering", `{"name":"x","filter":"foo=bar AND x>1"}`, "foo=bar AND x>1", false},
+		{"all operators", `{"name":"x","filter":"a<1 AND b<=2 AND c>=3 AND d:e"}`, "a<1 AND b<=2 AND c>=3 AND d:e", false},
+		{"! parse error", `{"name":"x","filter":"foo"}`, "", true},
+		{"! not a string", `{"name":"x","filter":42}`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got config
+			err := json.Unmarshal([]byte(tt.data), &got)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if got.Filter.Filter.String() != tt.want {
+				t.Errorf("Unmarshal() = %v, want %v", got.Filter.Filter, tt.want)
+			}
+			data, err := json.Marshal(got)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var again config
+			if err := json.Unmarshal(data, &again); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !again.Filter.Filter.Equal(got.Filter.Filter) {
+				t.Errorf("round trip = %v, want %v", again.Filter.Filter, got.Filter.Filter)
+			}
+		})
+	}
+}
+
+func TestText_UnmarshalText(t *testing.T) {
+	var txt Text
+	err := txt.UnmarshalText([]byte("foo=bar AND bla"))
+	var pe ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("expected ParseError, got %v", err)
+	}
+	if pe.Position() != 15 || pe.Message() != "expected operator" {
+		t.Errorf("unexpected ParseError %v", pe)
+	}
+	if txt.Filter != nil {
+		t.Errorf("expected no filter, got %v", txt.Filter)
+	}
+}
+
+func TestText_UnmarshalText_operators(t *testing.T) {
+	var txt Text
+	if err := txt.UnmarshalText([]byte("foo=bar AND x>1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cs := txt.Filter.Conditions()
+	if len(cs) != 2 || cs[1].Key() != "x" || cs[1].Op() != ">" || cs[1].StringValue() != "1" {
+		t.Errorf("UnmarshalText() = %v", txt.Filter)
+	}
+}
+
+func TestText_MarshalText(t *testing.T) {
+	got, err := Text{}.MarshalText()
+	if err != nil || string(got) != "" {
+		t.Errorf("MarshalText() = %s, %v", got, err)
+	}
+	data, err := json.Marshal(struct{ F Text }{})
+	if err != nil || string(data) != `{"F":""}` {
+		t.Errorf("Marshal() = %s, %v", data, err)
+	}
+}
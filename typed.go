@@ -0,0 +1,143 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// A SchemaType is the type a condition value is expected to have.
+type SchemaType int
+
+// The supported schema types.
+const (
+	TypeString SchemaType = iota
+	TypeInt
+	TypeFloat
+	TypeBool
+	TypeTimestamp
+)
+
+var schemaTypeNames = map[SchemaType]string{
+	TypeString:    "string",
+	TypeInt:       "int",
+	TypeFloat:     "float",
+	TypeBool:      "bool",
+	TypeTimestamp: "timestamp",
+}
+
+func (t SchemaType) String() string {
+	if s, ok := schemaTypeNames[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("SchemaType(%d)", int(t))
+}
+
+// A TypedCondition is a Condition with the type its value is expected to
+// have.
+type TypedCondition interface {
+	Condition
+	// ValueType returns the type the condition value is expected to have.
+	ValueType() SchemaType
+	// TypedEvaluate reports whether value satisfies the condition, using the
+	// evaluation for the value type: EvaluateString for strings, EvaluateInt
+	// for integers and EvaluateFloat for floats. Booleans and timestamps are
+	// compared to BoolValue and TimeValue, supporting '=' and '!=' and all
+	// ordering operators, respectively. According to the value type, the value
+	// must be a string, an integer, an integer or float, a bool or a
+	// time.Time. Otherwise, an error is returned.
+	TypedEvaluate(value any) (bool, error)
+}
+
+// NewTypedCondition returns c as a TypedCondition with value type t.
+func NewTypedCondition(c Condition, t SchemaType) TypedCondition {
+	return typedCondition{c, t}
+}
+
+type typedCondition struct {
+	Condition
+	t SchemaType
+}
+
+func (c typedCondition) ValueType() SchemaType {
+	return c.t
+}
+
+func (c typedCondition) TypedEvaluate(value any) (bool, error) {
+	v := reflect.ValueOf(value)
+	switch c.t {
+	case TypeString:
+		if v.Kind() == reflect.String {
+			return c.EvaluateString(v.String())
+		}
+	case TypeInt:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return c.EvaluateInt(v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			i, err := strconv.ParseInt(c.StringValue(), 10, 64)
+			if err != nil {
+				return false, fmt.Errorf("%s is not an integer", c.StringValue())
+			}
+			return compareUint(c.Op(), v.Uint(), i)
+		}
+	case TypeFloat:
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			return c.EvaluateFloat(v.Float())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return c.EvaluateFloat(float64(v.Int()))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return c.EvaluateFloat(float64(v.Uint()))
+		}
+	case TypeBool:
+		if v.Kind() == reflect.Bool {
+			b, err := c.BoolValue()
+			if err != nil {
+				return false, err
+			}
+			return compareBools(c.Op(), v.Bool(), b)
+		}
+	case TypeTimestamp:
+		if t, ok := value.(time.Time); ok {
+			ct, err := c.TimeValue()
+			if err != nil {
+				return false, err
+			}
+			return compareTimes(c.Op(), t, ct)
+		}
+	default:
+		return false, fmt.Errorf("unsupported value type %s", c.t)
+	}
+	return false, fmt.Errorf("expected %s value, got %T", c.t, value)
+}
+
+// TypedValue returns the condition value as the most specific type it is
+// valid for. Unquoted values are converted to a bool (see
+// Condition.BoolValue), int64, float64 or time.Time (for RFC 3339
+// timestamps), if possible. Other values, including quoted ones and dates, are
+// returned as strings.
+func TypedValue(c Condition) any {
+	if c.IsQuoted() {
+		return c.StringValue()
+	}
+	if b, err := c.BoolValue(); err == nil {
+		return b
+	}
+	if i, err := strconv.ParseInt(c.StringValue(), 10, 64); err == nil {
+		return i
+	}
+	if x, ok := number(c.StringValue()); ok && !math.IsInf(x, 0) {
+		return x
+	}
+	if t, err := time.Parse(time.RFC3339Nano, c.StringValue()); err == nil {
+		return t
+	}
+	return c.StringValue()
+}
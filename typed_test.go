@@ -0,0 +1,95 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTypedCondition_TypedEvaluate(t *testing.T) {
+	p := NewParser(OptionOperators("<", ">", "<=", ">="))
+	tests := []struct {
+		name    string
+		query   string
+		typ     SchemaType
+		value   any
+		want    bool
+		wantErr bool
+	}{
+		{"string", "name=foo", TypeString, "foo", true, false},
+		{"string, ordering", "name<foo", TypeString, "bar", true, false},
+		{"int", "size>9", TypeInt, 10, true, false},
+		{"int, sized", "size=10", TypeInt, int32(10), true, false},
+		{"int, unsigned", "size<10", TypeInt, uint8(10), false, false},
+		{"int, uint64", "size=10", TypeInt, uint64(10), true, false},
+		{"int, uint beyond int64", "size>9223372036854775807", TypeInt, uint64(math.MaxUint64), true, false},
+		{"int, uint beyond int64, equal", "size=9223372036854775807", TypeInt, uint(math.MaxUint64), false, false},
+		{"int, uint, negative value", "size>-1", TypeInt, uint32(0), true, false},
+		{"! int, uint, invalid value", "size=ten", TypeInt, uint64(10), false, true},
+		{"float", "score>=1.5", TypeFloat, 1.5, true, false},
+		{"float, int value", "score<1.5", TypeFloat, 1, true, false},
+		{"bool", "active=true", TypeBool, true, true, false},
+		{"bool, not equal", "active!=true", TypeBool, true, false, false},
+		{"timestamp", "created>2024-01-01", TypeTimestamp, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), true, false},
+		{"timestamp, time zone", "created=2024-03-01T13:00:00+01:00", TypeTimestamp, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), true, false},
+		{"! string, wrong type", "name=foo", TypeString, 42, false, true},
+		{"! int, wrong type", "size=10", TypeInt, 10.0, false, true},
+		{"! int, invalid value", "size=ten", TypeInt, 10, false, true},
+		{"! float, wrong type", "score=1", TypeFloat, "1", false, true},
+		{"! bool, ordering", "active<true", TypeBool, false, false, true},
+		{"! timestamp, wrong type", "created>2024-01-01", TypeTimestamp, "2024-03-01", false, true},
+		{"! timestamp, invalid value", "created>yesterday", TypeTimestamp, time.Now(), false, true},
+		{"! unknown type", "name=foo", SchemaType(42), "foo", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			c := NewTypedCondition(f.First(), tt.typ)
+			if c.ValueType() != tt.typ {
+				t.Errorf("ValueType() got = %v, want %v", c.ValueType(), tt.typ)
+			}
+			got, err := c.TypedEvaluate(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TypedEvaluate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("TypedEvaluate() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTypedValue(t *testing.T) {
+	tests := []struct {
+		query string
+		want  any
+	}{
+		{"a=foo", "foo"},
+		{`a="42"`, "42"},
+		{"a=true", true},
+		{"a=FALSE", false},
+		{"a=42", int64(42)},
+		{"a=-1.5", -1.5},
+		{"a=inf", "inf"},
+		{"a=2024-03-01T12:00:00Z", time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+		{"a=2024-03-01", "2024-03-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			f, err := NewParser().Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			if got := TypedValue(f.First()); got != tt.want {
+				t.Errorf("TypedValue() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// valuesPrefix is the prefix of the url.Values parameters of a filter.
+const valuesPrefix = "f."
+
+// EncodeValues encodes the filter as url.Values, with the parameters
+// f.<n>.key, f.<n>.op, f.<n>.value and, when set, f.<n>.quoted and f.<n>.sep
+// for the n-th condition (starting at 0). Like in the JSON encoding (see
+// FilterFromJSON), the separator links the condition to the next one. As no
+// filter string is involved, values need no quoting or escaping.
+func EncodeValues(f Filter) url.Values {
+	v := make(url.Values)
+	for i, c := range f.Conditions() {
+		p := valuesPrefix + strconv.Itoa(i) + "."
+		v.Set(p+"key", c.Key())
+		v.Set(p+"op", c.Op())
+		v.Set(p+"value", c.StringValue())
+		if c.IsQuoted() {
+			v.Set(p+"quoted", "true")
+		}
+		if and, or := c.AndOr(); and != nil {
+			v.Set(p+"sep", separatorAnd)
+		} else if or != nil {
+			v.Set(p+"sep", separatorOr)
+		}
+	}
+	return v
+}
+
+// DecodeValues decodes a Filter encoded by EncodeValues. Parameters without
+// the f. prefix are ignored. Keys must follow the naming rules of the filter
+// grammar and, like with the parser, only the operators '=' and '!=' are
+// accepted unless others are registered with OptionOperators.
+func DecodeValues(v url.Values, options ...Option) (Filter, error) {
+	n, keys := 0, 0
+	for k := range v {
+		if !strings.HasPrefix(k, valuesPrefix) {
+			continue
+		}
+		s := strings.TrimPrefix(k, valuesPrefix)
+		j := strings.IndexByte(s+".", '.')
+		i, err := strconv.Atoi(s[:j])
+		if err != nil || i < 0 {
+			return nil, fmt.Errorf("invalid filter parameter %q", k)
+		}
+		if i >= n {
+			n = i + 1
+		}
+		if s[j:] == ".key" {
+			keys += 1
+		}
+	}
+	// every condition needs a key, so this also bounds the allocation below
+	if n > keys {
+		return nil, fmt.Errorf("missing key for %d of %d conditions", n-keys, n)
+	}
+	p := NewParser(options...).(*parser)
+	cs := make([]condition, n)
+	seps := make([]string, 0, n)
+	for i := range cs {
+		pre := valuesPrefix + strconv.Itoa(i) + "."
+		quoted := v.Get(pre + "quoted")
+		if quoted != "" && quoted != "true" {
+			return nil, fmt.Errorf("condition %d: invalid quoted flag %q", i, quoted)
+		}
+		c, err := p.decodeCondition(v.Get(pre+"key"), v.Get(pre+"op"), v.Get(pre+"value"), quoted == "true")
+		if err != nil {
+			return nil, fmt.Errorf("condition %d: %v", i, err)
+		}
+		sep := v.Get(pre + "sep")
+		last := i == n-1
+		switch {
+		case last && sep != "":
+			return nil, fmt.Errorf("condition %d: unexpected separator %q after last condition", i, sep)
+		case !last && sep != separatorAnd && sep != separatorOr:
+			return nil, fmt.Errorf("condition %d: invalid separator %q, expected %s or %s", i, sep, separatorAnd, separatorOr)
+		case !last:
+			seps = append(seps, sep)
+		}
+		cs[i] = c
+	}
+	return newFilter(cs, seps), nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestEncodeValues(t *testing.T) {
+	f, _ := NewParser().Parse(`foo.bar=1 AND bla="x y" OR foo.bar!=2`)
+	got := EncodeValues(f).Encode()
+	want := "f.0.key=foo.bar&f.0.op=%3D&f.0.sep=AND&f.0.value=1&" +
+		"f.1.key=bla&f.1.op=%3D&f.1.quoted=true&f.1.sep=OR&f.1.value=x+y&" +
+		"f.2.key=foo.bar&f.2.op=%21%3D&f.2.value=2"
+	if got != want {
+		t.Errorf("EncodeValues() = %s, want %s", got, want)
+	}
+}
+
+func TestDecodeValues_roundTrip(t *testing.T) {
+	tests := []string{
+		"",
+		"foo=bar",
+		"foo.bar=1 AND bla=vla OR foo.bar=2",
+		"foo=1 AND foo=2 AND foo=1",
+		`foo="x y" OR bla="" AND moo=boo`,
+		`foo="a&b=c" AND bar="x=y&z" OR baz="%20+"`,
+		`foo="1" AND bar=1`,
+	}
+	for _, query := range tests {
+		t.Run(query, func(t *testing.T) {
+			f, err := NewParser().Parse(query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			v, err := url.ParseQuery(EncodeValues(f).Encode())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := DecodeValues(v)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.String() != f.String() {
+				t.Errorf("String() = %v, want %v", got, f)
+			}
+			if !got.Equal(f) {
+				t.Errorf("Equal() = false for %v and %v", got, f)
+			}
+			for i, c := range f.Conditions() {
+				if d := got.Conditions()[i]; d.IsQuoted() != c.IsQuoted() {
+					t.Errorf("IsQuoted() = %v for condition %d, want %v", d.IsQuoted(), i, c.IsQuoted())
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{"empty", "", "", false},
+		{"other parameters", "page=2&f.0.key=a&f.0.op=%3D&f.0.value=1", "a=1", false},
+		{"! invalid index", "f.x.key=a&f.x.op=%3D", "", true},
+		{"! gap", "f.0.key=a&f.0.op=%3D&f.0.sep=AND&f.2.key=b&f.2.op=%3D", "", true},
+		{"! unknown separator", "f.0.key=a&f.0.op=%3D&f.0.sep=XOR&f.1.key=b&f.1.op=%3D", "", true},
+		{"! missing separator", "f.0.key=a&f.0.op=%3D&f.1.key=b&f.1.op=%3D", "", true},
+		{"! trailing separator", "f.0.key=a&f.0.op=%3D&f.0.sep=AND", "", true},
+		{"! missing key", "f.0.op=%3D&f.0.value=1", "", true},
+		{"! missing operator", "f.0.key=a&f.0.value=1", "", true},
+		{"! invalid quoted flag", "f.0.key=a&f.0.op=%3D&f.0.quoted=yes", "", true},
+		{"! sparse index", "f.0.key=a&f.0.op=%3D&f.0.sep=AND&f.5000000.key=b&f.5000000.op=%3D", "", true},
+		{"! only value", "f.5000000.value=a", "", true},
+		{"! invalid key", "f.0.key=cn)(uid%3D*&f.0.op=%3D&f.0.value=x", "", true},
+		{"! unknown operator", "f.0.key=a&f.0.op=~~&f.0.value=x", "", true},
+		{"! unregistered operator", "f.0.key=a&f.0.op=%3C&f.0.value=1", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := DecodeValues(v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DecodeValues() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("DecodeValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeValues_operators(t *testing.T) {
+	f, _ := NewParser(OptionOperators("<")).Parse("a<1")
+	v := EncodeValues(f)
+	if _, err := DecodeValues(v); err == nil {
+		t.Errorf("expected error for unregistered operator")
+	}
+	got, err := DecodeValues(v, OptionOperators("<"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got.Equal(f) {
+		t.Errorf("Equal() = false for %v and %v", got, f)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import "fmt"
+
+// whereOperators maps filter operators to where input comparison operators.
+var whereOperators = map[string]string{
+	"=": "_eq", "!=": "_neq", "<": "_lt", ">": "_gt", "<=": "_lte", ">=": "_gte",
+}
+
+// ToWhereInput translates the filter into a GraphQL where input object in the
+// style of Hasura, like {"status": {"_eq": "open"}}, which can be encoded with
+// json.Marshal. Dotted keys result in nested objects. The OR groups are
+// merged into one object, with groups of more than one condition put in an
+// "_or" array. Groups that would overwrite an earlier one, like a second OR
+// group or a second bound of the same kind on a field, are put in an "_and"
+// array instead. Values are converted with TypedValue. The operators '=',
+// '!=', '<', '>', '<=' and '>=' are supported; for others, an error is
+// returned. An empty filter results in an empty object.
+func ToWhereInput(f Filter) (map[string]any, error) {
+	w := make(map[string]any)
+	var and []any
+	for _, g := range orGroups(f) {
+		var or []any
+		for _, c := range g {
+			m, err := whereCondition(c)
+			if err != nil {
+				return nil, err
+			}
+			or = append(or, m)
+		}
+		m := or[0].(map[string]any)
+		if len(or) > 1 {
+			m = map[string]any{"_or": or}
+		}
+		if !whereMerge(w, m) {
+			and = append(and, m)
+		}
+	}
+	if and != nil {
+		w["_and"] = and
+	}
+	return w, nil
+}
+
+// whereCondition translates a single condition into a where input object.
+func whereCondition(c Condition) (map[string]any, error) {
+	op, ok := whereOperators[c.Op()]
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported operator %s", c.Key(), c.Op())
+	}
+	var v any = map[string]any{op: TypedValue(c)}
+	ps := c.KeyParts()
+	for i := len(ps) - 1; i >= 0; i -= 1 {
+		v = map[string]any{ps[i]: v}
+	}
+	return v.(map[string]any), nil
+}
+
+// whereMerge merges src into dst, unless that would overwrite a value in dst.
+// It reports whether it did.
+func whereMerge(dst, src map[string]any) bool {
+	if !whereMergeable(dst, src) {
+		return false
+	}
+	for k, v := range src {
+		if d, ok := dst[k].(map[string]any); ok {
+			whereMerge(d, v.(map[string]any))
+		} else {
+			dst[k] = v
+		}
+	}
+	return true
+}
+
+// whereMergeable reports whether src can be merged into dst without
+// overwriting any values.
+func whereMergeable(dst, src map[string]any) bool {
+	for k, v := range src {
+		x, ok := dst[k]
+		if !ok {
+			continue
+		}
+		d, ok1 := x.(map[string]any)
+		s, ok2 := v.(map[string]any)
+		if !ok1 || !ok2 || !whereMergeable(d, s) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,78 @@
+// Copyright 2022 Hayo van Loon. All rights reserved.
+// Use of this source code is governed by an Apache
+// license that can be found in the LICENSE file.
+
+package listfilter
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestToWhereInput(t *testing.T) {
+	p := NewParser(OptionOperators("<", ">", "<=", ">=", ":"))
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{"empty", "", `{}`, false},
+		{"single", "status=open", `{"status":{"_eq":"open"}}`, false},
+		{
+			"typed values",
+			`a!=1 AND b<1.5 AND c=true AND d>"1" AND e>=2024-03-01T12:00:00Z AND f<=x`,
+			`{"a":{"_neq":1},"b":{"_lt":1.5},"c":{"_eq":true},"d":{"_gt":"1"},"e":{"_gte":"2024-03-01T12:00:00Z"},"f":{"_lte":"x"}}`,
+			false,
+		},
+		{"nested key", "owner.name=joe", `{"owner":{"name":{"_eq":"joe"}}}`, false},
+		{
+			"nested keys merged",
+			"owner.name=joe AND owner.age>=18 AND size>1 AND size<10",
+			`{"owner":{"age":{"_gte":18},"name":{"_eq":"joe"}},"size":{"_gt":1,"_lt":10}}`,
+			false,
+		},
+		{
+			"or on same field",
+			"status=open OR status=pending",
+			`{"_or":[{"status":{"_eq":"open"}},{"status":{"_eq":"pending"}}]}`,
+			false,
+		},
+		{
+			"mixed",
+			"owner.name=joe AND status=open OR status=pending",
+			`{"_or":[{"status":{"_eq":"open"}},{"status":{"_eq":"pending"}}],"owner":{"name":{"_eq":"joe"}}}`,
+			false,
+		},
+		{
+			"conflicts",
+			"a=1 OR a=2 AND b=1 OR b=2 AND c!=1 AND c!=2",
+			`{"_and":[{"_or":[{"b":{"_eq":1}},{"b":{"_eq":2}}]},{"c":{"_neq":2}}],` +
+				`"_or":[{"a":{"_eq":1}},{"a":{"_eq":2}}],"c":{"_neq":1}}`,
+			false,
+		},
+		{"! unsupported operator", "tags:x", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := p.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			w, err := ToWhereInput(f)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToWhereInput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got, err := json.Marshal(w)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ToWhereInput() got = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}